@@ -0,0 +1,144 @@
+// Package addressing validates and normalizes postal addresses according to the rules
+// of the country they are in.
+package addressing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Address holds the fields of a postal address. Country is an ISO 3166-1 alpha-2 code
+// once the address has been normalized.
+type Address struct {
+	FirstName     string
+	LastName      string
+	StreetAddress string
+	City          string
+	State         string
+	ZipCode       string
+	Country       string
+}
+
+// Errors maps the JSON name of each invalid address field to a message describing the
+// problem.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field + ": " + e[field]
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Normalize trims and collapses whitespace in every field, replaces the country with its
+// code, and brings the state and postal code into the form used in the country. It
+// returns Errors if the address is incomplete or not valid for its country.
+func Normalize(address *Address) error {
+	errs := Errors{}
+
+	for field, value := range map[string]*string{
+		"first_name":     &address.FirstName,
+		"last_name":      &address.LastName,
+		"street_address": &address.StreetAddress,
+		"city":           &address.City,
+		"state":          &address.State,
+		"zip_code":       &address.ZipCode,
+		"country":        &address.Country,
+	} {
+		*value = strings.Join(strings.Fields(*value), " ")
+		if *value == "" && field != "state" && field != "zip_code" {
+			errs[field] = "This field is required."
+		}
+	}
+
+	if address.Country != "" {
+		code, ok := CountryCode(address.Country)
+		if !ok {
+			errs["country"] = "Must be an ISO 3166-1 alpha-2 country code, such as NG."
+			return errs
+		}
+		address.Country = code
+
+		r := ruleFor(code)
+		if message := normalizeState(&address.State, r, code); message != "" {
+			errs["state"] = message
+		}
+		if message := normalizeZipCode(&address.ZipCode, r, code); message != "" {
+			errs["zip_code"] = message
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CountryCode returns the ISO 3166-1 alpha-2 code of a country given either its code,
+// in any case, or its English name.
+func CountryCode(country string) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(country))
+	if _, ok := countries[code]; ok {
+		return code, true
+	}
+
+	code, ok := countryNames[normalizeName(country)]
+	return code, ok
+}
+
+// CountryName returns the English name of the country with the given code.
+func CountryName(code string) string {
+	return countries[code]
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+func normalizeState(state *string, r rule, country string) string {
+	if *state == "" {
+		if r.stateRequired {
+			return "This field is required."
+		}
+		return ""
+	}
+
+	if r.states == nil {
+		return ""
+	}
+
+	*state = strings.ToUpper(*state)
+	if !r.states[*state] {
+		return fmt.Sprintf("Must be a state or province code in %s, such as %s.", CountryName(country), r.stateExample)
+	}
+	return ""
+}
+
+func normalizeZipCode(zipCode *string, r rule, country string) string {
+	if r.postalCode == nil {
+		*zipCode = ""
+		return ""
+	}
+
+	if *zipCode == "" {
+		return "This field is required."
+	}
+
+	*zipCode = strings.ToUpper(*zipCode)
+	if !r.postalCode.MatchString(*zipCode) {
+		return fmt.Sprintf("Must be a valid postal code in %s, such as %s.", CountryName(country), r.postalCodeExample)
+	}
+
+	if r.formatPostalCode != nil {
+		*zipCode = r.formatPostalCode(*zipCode)
+	}
+	return ""
+}
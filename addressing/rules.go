@@ -0,0 +1,168 @@
+package addressing
+
+import (
+	"regexp"
+	"strings"
+)
+
+// rule describes how addresses are written in one country.
+type rule struct {
+	// postalCode matches a valid postal code after normalization. Countries without
+	// postal codes have none, and any code given is dropped.
+	postalCode *regexp.Regexp
+	// postalCodeExample is shown in error messages.
+	postalCodeExample string
+	// formatPostalCode rewrites a valid postal code into its canonical form, for codes
+	// that are commonly written with or without a separator.
+	formatPostalCode func(string) string
+
+	// stateRequired is set for countries whose addresses need a state or province.
+	stateRequired bool
+	// states lists the valid state codes, for countries where they are well defined.
+	states       map[string]bool
+	stateExample string
+}
+
+// defaultRule applies to countries without rules of their own. Their postal codes are
+// required but only loosely checked.
+var defaultRule = rule{
+	postalCode:        regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`),
+	postalCodeExample: "12345",
+}
+
+// noPostalCode applies to countries that do not use postal codes.
+var noPostalCode = rule{}
+
+var (
+	fiveDigits = regexp.MustCompile(`^\d{5}$`)
+	fourDigits = regexp.MustCompile(`^\d{4}$`)
+	sixDigits  = regexp.MustCompile(`^\d{6}$`)
+)
+
+// separateAt returns a formatter that inserts sep before the last n characters of a
+// postal code, removing any separator the user typed.
+func separateAt(n int, sep string) func(string) string {
+	return func(code string) string {
+		code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+		return code[:len(code)-n] + sep + code[len(code)-n:]
+	}
+}
+
+func stateSet(codes ...string) map[string]bool {
+	states := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		states[code] = true
+	}
+	return states
+}
+
+var rules = map[string]rule{
+	"US": {
+		postalCode:        regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+		postalCodeExample: "94105",
+		stateRequired:     true,
+		states: stateSet(
+			"AL", "AK", "AZ", "AR", "CA", "CO", "CT", "DE", "DC", "FL", "GA", "HI", "ID", "IL", "IN",
+			"IA", "KS", "KY", "LA", "ME", "MD", "MA", "MI", "MN", "MS", "MO", "MT", "NE", "NV", "NH",
+			"NJ", "NM", "NY", "NC", "ND", "OH", "OK", "OR", "PA", "RI", "SC", "SD", "TN", "TX", "UT",
+			"VT", "VA", "WA", "WV", "WI", "WY", "AA", "AE", "AP",
+		),
+		stateExample: "CA",
+	},
+	"CA": {
+		postalCode:        regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] ?\d[ABCEGHJ-NPRSTV-Z]\d$`),
+		postalCodeExample: "K1A 0B1",
+		formatPostalCode:  separateAt(3, " "),
+		stateRequired:     true,
+		states:            stateSet("AB", "BC", "MB", "NB", "NL", "NS", "NT", "NU", "ON", "PE", "QC", "SK", "YT"),
+		stateExample:      "ON",
+	},
+	"AU": {
+		postalCode:        fourDigits,
+		postalCodeExample: "2000",
+		stateRequired:     true,
+		states:            stateSet("ACT", "NSW", "NT", "QLD", "SA", "TAS", "VIC", "WA"),
+		stateExample:      "NSW",
+	},
+	"BR": {
+		postalCode:        regexp.MustCompile(`^\d{5}-?\d{3}$`),
+		postalCodeExample: "01310-100",
+		formatPostalCode:  separateAt(3, "-"),
+		stateRequired:     true,
+		states: stateSet(
+			"AC", "AL", "AP", "AM", "BA", "CE", "DF", "ES", "GO", "MA", "MT", "MS", "MG", "PA",
+			"PB", "PR", "PE", "PI", "RJ", "RN", "RS", "RO", "RR", "SC", "SP", "SE", "TO",
+		),
+		stateExample: "SP",
+	},
+	"MX": {postalCode: fiveDigits, postalCodeExample: "06500", stateRequired: true},
+	"IN": {postalCode: sixDigits, postalCodeExample: "110001", stateRequired: true},
+	"GB": {
+		postalCode:        regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+		postalCodeExample: "SW1A 1AA",
+		formatPostalCode:  separateAt(3, " "),
+	},
+	"IE": {
+		postalCode:        regexp.MustCompile(`^(?:[AC-FHKNPRTV-Y]\d{2}|D6W) ?[0-9AC-FHKNPRTV-Y]{4}$`),
+		postalCodeExample: "D02 X285",
+		formatPostalCode:  separateAt(4, " "),
+	},
+	"NL": {
+		postalCode:        regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+		postalCodeExample: "1012 AB",
+		formatPostalCode:  separateAt(2, " "),
+	},
+	"SE": {
+		postalCode:        regexp.MustCompile(`^\d{3} ?\d{2}$`),
+		postalCodeExample: "111 22",
+		formatPostalCode:  separateAt(2, " "),
+	},
+	"JP": {
+		postalCode:        regexp.MustCompile(`^\d{3}-?\d{4}$`),
+		postalCodeExample: "100-0001",
+		formatPostalCode:  separateAt(4, "-"),
+	},
+	"PL": {postalCode: regexp.MustCompile(`^\d{2}-\d{3}$`), postalCodeExample: "00-950"},
+	"PT": {postalCode: regexp.MustCompile(`^\d{4}-\d{3}$`), postalCodeExample: "1000-001"},
+	"DE": {postalCode: fiveDigits, postalCodeExample: "10115"},
+	"FR": {postalCode: fiveDigits, postalCodeExample: "75001"},
+	"IT": {postalCode: fiveDigits, postalCodeExample: "00118"},
+	"ES": {postalCode: fiveDigits, postalCodeExample: "28001"},
+	"FI": {postalCode: fiveDigits, postalCodeExample: "00100"},
+	"KR": {postalCode: fiveDigits, postalCodeExample: "03187"},
+	"AT": {postalCode: fourDigits, postalCodeExample: "1010"},
+	"BE": {postalCode: fourDigits, postalCodeExample: "1000"},
+	"CH": {postalCode: fourDigits, postalCodeExample: "8001"},
+	"DK": {postalCode: fourDigits, postalCodeExample: "1050"},
+	"NO": {postalCode: fourDigits, postalCodeExample: "0150"},
+	"NZ": {postalCode: fourDigits, postalCodeExample: "6011"},
+	"ZA": {postalCode: fourDigits, postalCodeExample: "8001"},
+	"NG": {postalCode: sixDigits, postalCodeExample: "100001"},
+	"CN": {postalCode: sixDigits, postalCodeExample: "100000"},
+	"RU": {postalCode: sixDigits, postalCodeExample: "101000"},
+	"SG": {postalCode: sixDigits, postalCodeExample: "018956"},
+	"KE": {postalCode: fiveDigits, postalCodeExample: "00100"},
+
+	"AE": noPostalCode, "AG": noPostalCode, "AO": noPostalCode, "AW": noPostalCode,
+	"BF": noPostalCode, "BI": noPostalCode, "BJ": noPostalCode, "BO": noPostalCode,
+	"BS": noPostalCode, "BW": noPostalCode, "BZ": noPostalCode, "CD": noPostalCode,
+	"CF": noPostalCode, "CG": noPostalCode, "CI": noPostalCode, "CK": noPostalCode,
+	"CM": noPostalCode, "DJ": noPostalCode, "DM": noPostalCode, "ER": noPostalCode,
+	"FJ": noPostalCode, "GD": noPostalCode, "GH": noPostalCode, "GM": noPostalCode,
+	"GQ": noPostalCode, "GY": noPostalCode, "HK": noPostalCode, "KI": noPostalCode,
+	"KM": noPostalCode, "KN": noPostalCode, "KP": noPostalCode, "LC": noPostalCode,
+	"ML": noPostalCode, "MO": noPostalCode, "MR": noPostalCode, "MW": noPostalCode,
+	"NR": noPostalCode, "NU": noPostalCode, "QA": noPostalCode, "RW": noPostalCode,
+	"SB": noPostalCode, "SC": noPostalCode, "SL": noPostalCode, "SR": noPostalCode,
+	"SS": noPostalCode, "ST": noPostalCode, "SY": noPostalCode, "TD": noPostalCode,
+	"TG": noPostalCode, "TK": noPostalCode, "TL": noPostalCode, "TO": noPostalCode,
+	"TV": noPostalCode, "UG": noPostalCode, "VU": noPostalCode, "YE": noPostalCode,
+	"ZW": noPostalCode,
+}
+
+func ruleFor(country string) rule {
+	if r, ok := rules[country]; ok {
+		return r
+	}
+	return defaultRule
+}
@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportAccountData godoc
+// @Summary Export the current user's data
+// @Description Download the personal data held about the logged in user: their profile, addresses and orders. Use format=zip for an archive with the JSON document and a CSV file per record type.
+// @Tags Users
+// @Produce json
+// @Produce application/zip
+// @Param format query string false "Export format" Enums(json, zip) default(json)
+// @Success 200 {object} dtos.AccountDataExport "Successfully exported data"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid format"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/me/export [get]
+func ExportAccountData(c *gin.Context) {
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid format, expected json or zip"})
+		return
+	}
+
+	export, err := buildAccountDataExport(user)
+	if err != nil {
+		log.Printf("Failed to export data for user %v: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to export data"})
+		return
+	}
+
+	filename := fmt.Sprintf("account-%d-%s", user.ID, export.ExportedAt.Format("20060102"))
+
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".zip"))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+
+	if err := writeAccountDataArchive(zip.NewWriter(c.Writer), export); err != nil {
+		log.Printf("Failed to write data export archive for user %v: %v", user.ID, err)
+	}
+}
+
+// DeleteAccount godoc
+// @Summary Delete the current user's account
+// @Description Permanently deletes the logged in user's account after confirming their password. Personal data on the profile and saved addresses is erased, while orders are kept, detached from any identifying details, for accounting.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body dtos.DeleteAccountRequest true "Current password"
+// @Success 200 {object} dtos.MessageResponse "Account deleted successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, or the password is incorrect"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/me [delete]
+func DeleteAccount(c *gin.Context) {
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	if err := user.IsValidPassword(req.Password); err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Password is incorrect"})
+		return
+	}
+
+	if err := anonymizeUser(&user); err != nil {
+		log.Printf("Failed to delete account of user %v: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to delete account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "Account deleted successfully"})
+}
+
+func buildAccountDataExport(user models.User) (*dtos.AccountDataExport, error) {
+	export := dtos.AccountDataExport{
+		ExportedAt:         time.Now().UTC(),
+		Profile:            user,
+		Addresses:          []models.Address{},
+		Orders:             []models.Order{},
+		ExternalIdentities: []models.ExternalIdentity{},
+	}
+
+	if err := db.DB.Model(&user).Association("Roles").Find(&export.Profile.Roles); err != nil {
+		return nil, err
+	}
+
+	if err := db.DB.Where("user_id = ?", user.ID).Order("id").Find(&export.Addresses).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.DB.Where("user_id = ?", user.ID).Order("id").Find(&export.ExternalIdentities).Error; err != nil {
+		return nil, err
+	}
+
+	err := db.DB.Preload("OrderItems").Where("user_id = ?", user.ID).Order("id").Find(&export.Orders).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// The profile is exported once; repeating it on every order adds nothing
+	for i := range export.Orders {
+		export.Orders[i].User = models.User{}
+	}
+
+	return &export, nil
+}
+
+// writeAccountDataArchive writes the export as data.json plus one CSV file per record type.
+func writeAccountDataArchive(archive *zip.Writer, export *dtos.AccountDataExport) error {
+	document, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(document)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return err
+	}
+
+	timestamp := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+	optionalTimestamp := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return timestamp(*t)
+	}
+	optionalID := func(id *uint) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.Itoa(int(*id))
+	}
+
+	profile := export.Profile
+	tables := map[string][][]string{
+		"profile.csv": {
+			{"id", "email", "first_name", "last_name", "role", "created_at", "last_login", "email_verified_at"},
+			{strconv.Itoa(int(profile.ID)), profile.Email, profile.FirstName, profile.LastName, profile.Role,
+				timestamp(profile.CreatedAt), timestamp(profile.LastLogin), optionalTimestamp(profile.EmailVerifiedAt)},
+		},
+		"addresses.csv": {{"id", "first_name", "last_name", "street_address", "city", "state", "zip_code", "country", "created_at"}},
+		"orders.csv": {
+			{"id", "status", "total", "address_id",
+				"shipping_first_name", "shipping_last_name", "shipping_street_address", "shipping_city", "shipping_state", "shipping_zip_code", "shipping_country",
+				"billing_first_name", "billing_last_name", "billing_street_address", "billing_city", "billing_state", "billing_zip_code", "billing_country",
+				"created_at"},
+		},
+		"order_items.csv": {{"order_id", "product_id", "price", "quantity", "variant_id", "sku"}},
+	}
+
+	for _, address := range export.Addresses {
+		tables["addresses.csv"] = append(tables["addresses.csv"], []string{
+			strconv.Itoa(int(address.ID)), address.FirstName, address.LastName, address.StreetAddress,
+			address.City, address.State, address.ZipCode, address.Country, timestamp(address.CreatedAt),
+		})
+	}
+
+	for _, order := range export.Orders {
+		shipping, billing := order.ShippingAddress, order.BillingAddress
+		tables["orders.csv"] = append(tables["orders.csv"], []string{
+			strconv.Itoa(int(order.ID)), order.Status, strconv.FormatFloat(order.Total, 'f', 2, 64),
+			strconv.Itoa(int(order.AddressID)),
+			shipping.FirstName, shipping.LastName, shipping.StreetAddress, shipping.City, shipping.State, shipping.ZipCode, shipping.Country,
+			billing.FirstName, billing.LastName, billing.StreetAddress, billing.City, billing.State, billing.ZipCode, billing.Country,
+			timestamp(order.CreatedAt),
+		})
+
+		for _, item := range order.OrderItems {
+			tables["order_items.csv"] = append(tables["order_items.csv"], []string{
+				strconv.Itoa(int(item.OrderID)), strconv.Itoa(int(item.ProductID)),
+				strconv.FormatFloat(item.Price, 'f', 2, 64), strconv.Itoa(item.Quantity),
+				optionalID(item.VariantID), item.SKU,
+			})
+		}
+	}
+
+	for _, name := range []string{"profile.csv", "addresses.csv", "orders.csv", "order_items.csv"} {
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+
+		if err := csv.NewWriter(file).WriteAll(tables[name]); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// anonymizeUser erases the personal data held about a user while keeping the user and
+// address rows that their orders reference, and ends every way of acting as the user.
+func anonymizeUser(user *models.User) error {
+	now := time.Now()
+	originalEmail := user.Email
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).Updates(map[string]interface{}{
+			"email":              fmt.Sprintf("deleted-user-%d@deleted.invalid", user.ID),
+			"first_name":         "Deleted",
+			"last_name":          "User",
+			"password":           "",
+			"email_verified_at":  nil,
+			"mfa_secret":         "",
+			"mfa_enabled_at":     nil,
+			"suspension_reason":  "",
+			"anonymized_at":      now,
+			"tokens_valid_after": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Address{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+			"first_name":     "Deleted",
+			"last_name":      "User",
+			"street_address": "Redacted",
+			"zip_code":       "",
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// Orders keep their own copies of the addresses; redact them the same way
+		redacted := map[string]interface{}{}
+		for _, prefix := range []string{"shipping_", "billing_"} {
+			redacted[prefix+"first_name"] = "Deleted"
+			redacted[prefix+"last_name"] = "User"
+			redacted[prefix+"street_address"] = "Redacted"
+			redacted[prefix+"zip_code"] = ""
+		}
+		if err := tx.Model(&models.Order{}).Where("user_id = ?", user.ID).Updates(redacted).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.AdminInvitation{}).Where("used_by_id = ?", user.ID).Update("email", "").Error
+		if err != nil {
+			return err
+		}
+
+		// Sessions record IP addresses and user agents, and the remaining records hold
+		// email addresses, secrets or links to external accounts that are of no further use
+		for _, record := range []interface{}{
+			&models.Session{}, &models.EmailVerificationToken{}, &models.PasswordResetToken{},
+			&models.MFARecoveryCode{}, &models.ExternalIdentity{},
+		} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(record).Error; err != nil {
+				return err
+			}
+		}
+
+		emailKey := emailThrottleKey(originalEmail)
+		if err := tx.Where(map[string]interface{}{"key": emailKey}).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where(map[string]interface{}{"key": emailKey}).Delete(&models.AccountLockout{}).Error
+	})
+}
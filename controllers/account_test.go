@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAccountData(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.Product{}, &models.ProductImage{}, &models.Order{}, &models.OrderItem{}, &models.Address{},
+		&models.RefreshToken{}, &models.RevokedToken{}, &models.Session{}, &models.APIKey{}, &models.AdminInvitation{},
+		&models.EmailVerificationToken{}, &models.PasswordResetToken{}, &models.MFARecoveryCode{}, &models.LoginThrottle{}, &models.AccountLockout{}, &models.ExternalIdentity{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	user := models.User{Email: "user@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&user)
+
+	address := models.Address{FirstName: "John", LastName: "Doe", City: "Lagos", Country: "NG", ZipCode: "100001", StreetAddress: "1 Marina", UserID: user.ID}
+	mockDB.Create(&address)
+
+	product := models.Product{Name: "Shirt", Description: "Cotton", Price: 10, Stock: 5}
+	mockDB.Create(&product)
+
+	snapshot := models.NewOrderAddress(address)
+	order := models.Order{UserID: user.ID, AddressID: address.ID, ShippingAddress: snapshot, BillingAddress: snapshot, Total: 20, Status: models.OrderStatusCompleted}
+	mockDB.Create(&order)
+	mockDB.Create(&models.OrderItem{OrderID: order.ID, ProductID: product.ID, Price: 10, Quantity: 2})
+
+	tokens, _ := issueTokens(newTestContext(), &user, "")
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(middleware.LoadAuthUserMiddleware())
+	router.POST("/login", LoginUser)
+	router.GET("/users/me/export", ExportAccountData)
+	router.DELETE("/users/me", DeleteAccount)
+
+	request := func(method, path string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Exports profile, addresses and orders as JSON", func(t *testing.T) {
+		rec := request("GET", "/users/me/export", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Disposition"), ".json")
+
+		var export dtos.AccountDataExport
+		json.Unmarshal(rec.Body.Bytes(), &export)
+		assert.Equal(t, user.Email, export.Profile.Email)
+		assert.Len(t, export.Addresses, 1)
+		assert.Len(t, export.Orders, 1)
+		assert.Len(t, export.Orders[0].OrderItems, 1)
+		assert.NotContains(t, rec.Body.String(), string(hashedPassword))
+	})
+
+	t.Run("Exports a zip archive of CSV files", func(t *testing.T) {
+		rec := request("GET", "/users/me/export?format=zip", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+		archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+		assert.NoError(t, err)
+
+		files := map[string][][]string{}
+		for _, file := range archive.File {
+			content, _ := file.Open()
+			records, _ := csv.NewReader(content).ReadAll()
+			files[file.Name] = records
+			content.Close()
+		}
+
+		assert.Contains(t, files, "data.json")
+		assert.Equal(t, user.Email, files["profile.csv"][1][1])
+		assert.Equal(t, "1 Marina", files["addresses.csv"][1][3])
+		assert.Equal(t, "20.00", files["orders.csv"][1][2])
+		assert.Equal(t, "2", files["order_items.csv"][1][3])
+
+		rec = request("GET", "/users/me/export?format=xml", nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Deleting the account requires login before validating the request", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/users/me", bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Deleting the account anonymizes it and keeps orders", func(t *testing.T) {
+		rec := request("DELETE", "/users/me", dtos.DeleteAccountRequest{Password: "wrongpassword"})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("DELETE", "/users/me", dtos.DeleteAccountRequest{Password: "password"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var deleted models.User
+		mockDB.First(&deleted, user.ID)
+		assert.True(t, deleted.IsAnonymized())
+		assert.NotEqual(t, user.Email, deleted.Email)
+		assert.Equal(t, "Deleted", deleted.FirstName)
+
+		var redacted models.Address
+		mockDB.First(&redacted, address.ID)
+		assert.Equal(t, "Redacted", redacted.StreetAddress)
+		assert.Equal(t, "NG", redacted.Country)
+
+		var keptOrder models.Order
+		mockDB.First(&keptOrder, order.ID)
+		assert.Equal(t, "Redacted", keptOrder.ShippingAddress.StreetAddress)
+		assert.Equal(t, "Redacted", keptOrder.BillingAddress.StreetAddress)
+		assert.Equal(t, "NG", keptOrder.ShippingAddress.Country)
+
+		var orderCount, itemCount, sessionCount int64
+		mockDB.Model(&models.Order{}).Where("user_id = ?", user.ID).Count(&orderCount)
+		mockDB.Model(&models.OrderItem{}).Where("order_id = ?", order.ID).Count(&itemCount)
+		mockDB.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&sessionCount)
+		assert.Equal(t, int64(1), orderCount)
+		assert.Equal(t, int64(1), itemCount)
+		assert.Zero(t, sessionCount)
+
+		rec = request("GET", "/users/me/export", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("POST", "/login", dtos.LoginRequest{Email: user.Email, Password: "password"})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -2,11 +2,14 @@ package controllers
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/cgzirim/ecommerce-api/addressing"
 	"github.com/cgzirim/ecommerce-api/db"
 	"github.com/cgzirim/ecommerce-api/dtos"
 	"github.com/cgzirim/ecommerce-api/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ListAddresses godoc
@@ -17,18 +20,16 @@ import (
 // @Produce json
 // @Success 200 {array} dtos.AddressDetail "Successfully retrieved addresses"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /users/addresses [get]
 func ListAddresses(c *gin.Context) {
-	authUser, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+	user, ok := requireUserSession(c)
+	if !ok {
 		return
 	}
 
-	user := authUser.(models.User)
-
 	var addresses []models.Address
 	if err := db.DB.Where("user_id = ?", user.ID).Find(&addresses).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve addresses"})
@@ -40,46 +41,301 @@ func ListAddresses(c *gin.Context) {
 
 // CreateAddress godoc
 // @Summary Create a new address
-// @Description Create a new address for the logged in user
+// @Description Create a new address for the logged in user. The country is given as an ISO 3166-1 alpha-2 code or its English name and stored as the code. Whether a state and zip code are required, and what they look like, depends on the country.
 // @Tags User
 // @Accept json
 // @Produce json
 // @Param address body dtos.CreateAddressRequest true "Address information"
 // @Success 201 {object} dtos.AddressDetail "Address created successfully"
-// @Failure 400 {object} dtos.ErrorResponse "Invalid input"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error, or an address that is not valid for its country"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /users/addresses [post]
 func CreateAddress(c *gin.Context) {
-	var request dtos.CreateAddressRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid input data"})
+	user, ok := requireUserSession(c)
+	if !ok {
 		return
 	}
 
-	authUser, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+	var request dtos.CreateAddressRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		handleValidationErrors(err, c)
 		return
 	}
 
-	user := authUser.(models.User)
-
 	address := models.Address{
-		FirstName:     request.FirstName,
-		LastName:      request.LastName,
-		City:          request.City,
-		Country:       request.Country,
-		ZipCode:       request.ZipCode,
-		StreetAddress: request.StreetAddress,
-		UserID:        user.ID,
+		FirstName:         request.FirstName,
+		LastName:          request.LastName,
+		City:              request.City,
+		State:             request.State,
+		Country:           request.Country,
+		ZipCode:           request.ZipCode,
+		StreetAddress:     request.StreetAddress,
+		IsDefaultShipping: request.IsDefaultShipping,
+		IsDefaultBilling:  request.IsDefaultBilling,
+		UserID:            user.ID,
+	}
+
+	if !normalizeAddress(c, &address) {
+		return
 	}
 
-	if err := db.DB.Create(&address).Error; err != nil {
+	if err := saveAddress(&address); err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to create address"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, address)
 }
+
+// GetAddress godoc
+// @Summary Get an address
+// @Description Retrieve one of the logged in user's addresses.
+// @Tags User
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 200 {object} dtos.AddressDetail "Successfully retrieved address"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid address ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
+// @Failure 404 {object} dtos.ErrorResponse "Address not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/addresses/{id} [get]
+func GetAddress(c *gin.Context) {
+	address, ok := findOwnAddress(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// ReplaceAddress godoc
+// @Summary Replace an address
+// @Description Replace all fields of one of the logged in user's addresses. Fields are validated as when creating an address. Making it a default address unsets the previous default.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path int true "Address ID"
+// @Param address body dtos.CreateAddressRequest true "Address information"
+// @Success 200 {object} dtos.AddressDetail "Address updated successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error, or an address that is not valid for its country"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
+// @Failure 404 {object} dtos.ErrorResponse "Address not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/addresses/{id} [put]
+func ReplaceAddress(c *gin.Context) {
+	var request dtos.CreateAddressRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	address, ok := findOwnAddress(c)
+	if !ok {
+		return
+	}
+
+	address.FirstName = request.FirstName
+	address.LastName = request.LastName
+	address.City = request.City
+	address.State = request.State
+	address.Country = request.Country
+	address.ZipCode = request.ZipCode
+	address.StreetAddress = request.StreetAddress
+	address.IsDefaultShipping = request.IsDefaultShipping
+	address.IsDefaultBilling = request.IsDefaultBilling
+
+	if !normalizeAddress(c, &address) {
+		return
+	}
+
+	if err := saveAddress(&address); err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// UpdateAddress godoc
+// @Summary Update an address
+// @Description Update some fields of one of the logged in user's addresses. Omitted fields are left unchanged, and the resulting address is validated as when creating one. Making it a default address unsets the previous default.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path int true "Address ID"
+// @Param address body dtos.UpdateAddressRequest true "Fields to update"
+// @Success 200 {object} dtos.AddressDetail "Address updated successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error, or an address that is not valid for its country"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
+// @Failure 404 {object} dtos.ErrorResponse "Address not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/addresses/{id} [patch]
+func UpdateAddress(c *gin.Context) {
+	var request dtos.UpdateAddressRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	address, ok := findOwnAddress(c)
+	if !ok {
+		return
+	}
+
+	if request.FirstName != nil {
+		address.FirstName = *request.FirstName
+	}
+	if request.LastName != nil {
+		address.LastName = *request.LastName
+	}
+	if request.City != nil {
+		address.City = *request.City
+	}
+	if request.State != nil {
+		address.State = *request.State
+	}
+	if request.Country != nil {
+		address.Country = *request.Country
+	}
+	if request.ZipCode != nil {
+		address.ZipCode = *request.ZipCode
+	}
+	if request.StreetAddress != nil {
+		address.StreetAddress = *request.StreetAddress
+	}
+	if request.IsDefaultShipping != nil {
+		address.IsDefaultShipping = *request.IsDefaultShipping
+	}
+	if request.IsDefaultBilling != nil {
+		address.IsDefaultBilling = *request.IsDefaultBilling
+	}
+
+	if !normalizeAddress(c, &address) {
+		return
+	}
+
+	if err := saveAddress(&address); err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// DeleteAddress godoc
+// @Summary Delete an address
+// @Description Delete one of the logged in user's addresses. Orders keep their own copy of the address, so orders placed with it are not affected.
+// @Tags User
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 200 {object} dtos.MessageResponse "Address deleted successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid address ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
+// @Failure 404 {object} dtos.ErrorResponse "Address not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/addresses/{id} [delete]
+func DeleteAddress(c *gin.Context) {
+	address, ok := findOwnAddress(c)
+	if !ok {
+		return
+	}
+
+	if err := db.DB.Delete(&address).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to delete address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "Address deleted successfully"})
+}
+
+// findOwnAddress loads the address named by the :id path parameter, writing an error
+// response if there is none or it belongs to another user. Other users' addresses are
+// reported as not found so that their existence is not revealed.
+func findOwnAddress(c *gin.Context) (models.Address, bool) {
+	var address models.Address
+
+	addressID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || addressID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid address ID"})
+		return address, false
+	}
+
+	user, ok := requireUserSession(c)
+	if !ok {
+		return address, false
+	}
+
+	if err := db.DB.Where("id = ? AND user_id = ?", addressID, user.ID).Limit(1).Find(&address).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve address"})
+		return address, false
+	}
+	if address.ID == 0 {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Address not found"})
+		return address, false
+	}
+
+	return address, true
+}
+
+// normalizeAddress checks address against the rules of its country and normalizes it,
+// writing field-level errors in the same shape as handleValidationErrors otherwise.
+func normalizeAddress(c *gin.Context, address *models.Address) bool {
+	fields := addressing.Address{
+		FirstName:     address.FirstName,
+		LastName:      address.LastName,
+		StreetAddress: address.StreetAddress,
+		City:          address.City,
+		State:         address.State,
+		ZipCode:       address.ZipCode,
+		Country:       address.Country,
+	}
+
+	if err := addressing.Normalize(&fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err})
+		return false
+	}
+
+	address.FirstName = fields.FirstName
+	address.LastName = fields.LastName
+	address.StreetAddress = fields.StreetAddress
+	address.City = fields.City
+	address.State = fields.State
+	address.ZipCode = fields.ZipCode
+	address.Country = fields.Country
+	return true
+}
+
+// saveAddress creates or updates address. If it is a default address, the user's
+// previous default of the same kind stops being one.
+func saveAddress(address *models.Address) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		for column, isDefault := range map[string]bool{
+			"is_default_shipping": address.IsDefaultShipping,
+			"is_default_billing":  address.IsDefaultBilling,
+		} {
+			if !isDefault {
+				continue
+			}
+
+			err := tx.Model(&models.Address{}).
+				Where("user_id = ? AND id <> ? AND "+column, address.UserID, address.ID).
+				Update(column, false).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Save(address).Error
+	})
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -29,8 +30,8 @@ func TestListAddresses(t *testing.T) {
 	mockDB.Create(&user)
 
 	addresses := []models.Address{
-		{FirstName: "John", LastName: "Doe", City: "CityA", Country: "CountryA", ZipCode: "12345", StreetAddress: "Street 1", UserID: user.ID},
-		{FirstName: "Jane", LastName: "Doe", City: "CityB", Country: "CountryB", ZipCode: "67890", StreetAddress: "Street 2", UserID: user.ID},
+		{FirstName: "John", LastName: "Doe", City: "CityA", Country: "DE", ZipCode: "12345", StreetAddress: "Street 1", UserID: user.ID},
+		{FirstName: "Jane", LastName: "Doe", City: "CityB", Country: "DE", ZipCode: "67890", StreetAddress: "Street 2", UserID: user.ID},
 	}
 	for _, addr := range addresses {
 		mockDB.Create(&addr)
@@ -115,7 +116,7 @@ func TestCreateAddress(t *testing.T) {
 			FirstName:     "John",
 			LastName:      "Doe",
 			City:          "CityA",
-			Country:       "CountryA",
+			Country:       "DE",
 			ZipCode:       "12345",
 			StreetAddress: "Street 1",
 		}
@@ -161,10 +162,10 @@ func TestCreateAddress(t *testing.T) {
 
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 
-		var response dtos.ErrorResponse
+		var response map[string]map[string]string
 		err := json.Unmarshal(rec.Body.Bytes(), &response)
 		assert.NoError(t, err)
-		assert.Equal(t, "Invalid input data", response.Error)
+		assert.Equal(t, "This field is required.", response["error"]["country"])
 	})
 
 	t.Run("Fails when user is unauthenticated", func(t *testing.T) {
@@ -177,7 +178,7 @@ func TestCreateAddress(t *testing.T) {
 			FirstName:     "John",
 			LastName:      "Doe",
 			City:          "CityA",
-			Country:       "CountryA",
+			Country:       "DE",
 			ZipCode:       "12345",
 			StreetAddress: "Street 1",
 		}
@@ -195,3 +196,188 @@ func TestCreateAddress(t *testing.T) {
 		assert.Equal(t, "Unauthenticated, login is required", response.Error)
 	})
 }
+
+func TestManageAddress(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.Address{}, &models.User{}, &models.Order{})
+
+	// Override the global DB variable with the mock DB and reset it after the test
+	originalDB := db.DB
+	defer func() { db.DB = originalDB }()
+	db.SetMockDB(mockDB)
+
+	user := models.User{Email: "test@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: "password"}
+	mockDB.Create(&user)
+
+	other := models.User{Email: "other@example.com", FirstName: "Jane", LastName: "Roe", Role: "customer", Password: "password"}
+	mockDB.Create(&other)
+
+	home := models.Address{FirstName: "John", LastName: "Doe", City: "CityA", Country: "DE", ZipCode: "12345", StreetAddress: "Street 1", UserID: user.ID, IsDefaultShipping: true, IsDefaultBilling: true}
+	mockDB.Create(&home)
+
+	work := models.Address{FirstName: "John", LastName: "Doe", City: "CityB", Country: "DE", ZipCode: "67890", StreetAddress: "Stret 2", UserID: user.ID}
+	mockDB.Create(&work)
+
+	othersAddress := models.Address{FirstName: "Jane", LastName: "Roe", City: "CityC", Country: "DE", ZipCode: "11111", StreetAddress: "Street 3", UserID: other.ID}
+	mockDB.Create(&othersAddress)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", user)
+	})
+	router.POST("/users/addresses", CreateAddress)
+	router.GET("/users/addresses/:id", GetAddress)
+	router.PUT("/users/addresses/:id", ReplaceAddress)
+	router.PATCH("/users/addresses/:id", UpdateAddress)
+	router.DELETE("/users/addresses/:id", DeleteAddress)
+
+	request := func(method, path string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	reload := func(address models.Address) models.Address {
+		var reloaded models.Address
+		mockDB.First(&reloaded, address.ID)
+		return reloaded
+	}
+
+	t.Run("Only exposes the user's own addresses", func(t *testing.T) {
+		rec := request("GET", fmt.Sprintf("/users/addresses/%d", home.ID), nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		replacement := dtos.CreateAddressRequest{FirstName: "X", LastName: "X", City: "X", Country: "X", ZipCode: "X", StreetAddress: "X"}
+		for _, method := range []string{"GET", "PUT", "PATCH", "DELETE"} {
+			rec = request(method, fmt.Sprintf("/users/addresses/%d", othersAddress.ID), replacement)
+			assert.Equal(t, http.StatusNotFound, rec.Code, method)
+		}
+
+		assert.Equal(t, "Street 3", reload(othersAddress).StreetAddress)
+	})
+
+	t.Run("Partially updates an address", func(t *testing.T) {
+		street := "Street 2"
+		rec := request("PATCH", fmt.Sprintf("/users/addresses/%d", work.ID), dtos.UpdateAddressRequest{StreetAddress: &street})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		updated := reload(work)
+		assert.Equal(t, "Street 2", updated.StreetAddress)
+		assert.Equal(t, "CityB", updated.City)
+	})
+
+	t.Run("Keeps at most one default address of each kind", func(t *testing.T) {
+		isDefault := true
+		rec := request("PATCH", fmt.Sprintf("/users/addresses/%d", work.ID), dtos.UpdateAddressRequest{IsDefaultShipping: &isDefault})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		assert.True(t, reload(work).IsDefaultShipping)
+		assert.False(t, reload(home).IsDefaultShipping)
+		assert.True(t, reload(home).IsDefaultBilling)
+
+		rec = request("POST", "/users/addresses", dtos.CreateAddressRequest{
+			FirstName: "John", LastName: "Doe", City: "CityD", Country: "DE", ZipCode: "22222", StreetAddress: "Street 4", IsDefaultBilling: true,
+		})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var created models.Address
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		assert.True(t, created.IsDefaultBilling)
+		assert.False(t, reload(home).IsDefaultBilling)
+
+		// Replacing an address without the flag clears it
+		rec = request("PUT", fmt.Sprintf("/users/addresses/%d", created.ID), dtos.CreateAddressRequest{
+			FirstName: "John", LastName: "Doe", City: "CityE", Country: "DE", ZipCode: "33333", StreetAddress: "Street 5",
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.False(t, reload(created).IsDefaultBilling)
+		assert.Equal(t, "CityE", reload(created).City)
+	})
+
+	t.Run("Validates and normalizes addresses by country", func(t *testing.T) {
+		create := func(payload dtos.CreateAddressRequest) (models.Address, map[string]string) {
+			payload.FirstName, payload.LastName, payload.City = "John", "Doe", "City"
+			if payload.StreetAddress == "" {
+				payload.StreetAddress = "Street 1"
+			}
+
+			rec := request("POST", "/users/addresses", payload)
+			if rec.Code != http.StatusCreated {
+				var response map[string]map[string]string
+				json.Unmarshal(rec.Body.Bytes(), &response)
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				return models.Address{}, response["error"]
+			}
+
+			var created models.Address
+			json.Unmarshal(rec.Body.Bytes(), &created)
+			return created, nil
+		}
+
+		created, errs := create(dtos.CreateAddressRequest{Country: " nigeria ", ZipCode: "100001", StreetAddress: "  12   Marina  Road "})
+		assert.Nil(t, errs)
+		assert.Equal(t, "NG", created.Country)
+		assert.Equal(t, "12 Marina Road", created.StreetAddress)
+
+		_, errs = create(dtos.CreateAddressRequest{Country: "Narnia", ZipCode: "12345"})
+		assert.Equal(t, "Must be an ISO 3166-1 alpha-2 country code, such as NG.", errs["country"])
+
+		_, errs = create(dtos.CreateAddressRequest{Country: "NG"})
+		assert.Equal(t, "This field is required.", errs["zip_code"])
+
+		_, errs = create(dtos.CreateAddressRequest{Country: "us", ZipCode: "9410"})
+		assert.Equal(t, "This field is required.", errs["state"])
+		assert.Equal(t, "Must be a valid postal code in United States, such as 94105.", errs["zip_code"])
+
+		_, errs = create(dtos.CreateAddressRequest{Country: "US", State: "XX", ZipCode: "94105"})
+		assert.Equal(t, "Must be a state or province code in United States, such as CA.", errs["state"])
+
+		created, errs = create(dtos.CreateAddressRequest{Country: "us", State: "ca", ZipCode: "94105-1234"})
+		assert.Nil(t, errs)
+		assert.Equal(t, "US", created.Country)
+		assert.Equal(t, "CA", created.State)
+
+		created, errs = create(dtos.CreateAddressRequest{Country: "Canada", State: "on", ZipCode: "k1a0b1"})
+		assert.Nil(t, errs)
+		assert.Equal(t, "K1A 0B1", created.ZipCode)
+
+		// Countries without postal codes drop whatever was entered
+		created, errs = create(dtos.CreateAddressRequest{Country: "HK", ZipCode: "000000"})
+		assert.Nil(t, errs)
+		assert.Empty(t, created.ZipCode)
+
+		// Partial updates validate the resulting address, normalizing older entries
+		legacy := models.Address{FirstName: "John", LastName: "Doe", City: "Lagos", Country: "Nigeria", ZipCode: "100001", StreetAddress: "Street 6", UserID: user.ID}
+		mockDB.Create(&legacy)
+
+		city := "Abuja"
+		rec := request("PATCH", fmt.Sprintf("/users/addresses/%d", legacy.ID), dtos.UpdateAddressRequest{City: &city})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "NG", reload(legacy).Country)
+
+		country := "GB"
+		rec = request("PATCH", fmt.Sprintf("/users/addresses/%d", legacy.ID), dtos.UpdateAddressRequest{Country: &country})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "NG", reload(legacy).Country)
+	})
+
+	t.Run("Deletes an address even if a pending order ships to it", func(t *testing.T) {
+		order := models.Order{UserID: user.ID, AddressID: home.ID, ShippingAddress: models.NewOrderAddress(home), Total: 10}
+		mockDB.Create(&order)
+
+		rec := request("DELETE", fmt.Sprintf("/users/addresses/%d", home.ID), nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = request("GET", fmt.Sprintf("/users/addresses/%d", home.ID), nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		var reloaded models.Order
+		mockDB.First(&reloaded, order.ID)
+		assert.Equal(t, home.StreetAddress, reloaded.ShippingAddress.StreetAddress)
+	})
+}
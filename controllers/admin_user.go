@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// userSortColumns maps the sort keys accepted by ListUsers to their columns.
+var userSortColumns = map[string]string{
+	"email":      "email",
+	"first_name": "first_name",
+	"last_name":  "last_name",
+	"role":       "role",
+	"created_at": "created_at",
+	"last_login": "last_login",
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description Allows an admin to search users with pagination, filters and sorting. Text filters match case-insensitively anywhere in the field.
+// @Tags Admin
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Number of users per page" default(10)
+// @Param email query string false "Filter by email"
+// @Param name query string false "Filter by first or last name"
+// @Param role query string false "Filter by base role"
+// @Param status query string false "Filter by account status" Enums(active, suspended)
+// @Param sort query string false "Sort key, prefixed with - for descending order" Enums(email, first_name, last_name, role, created_at, last_login) default(-created_at)
+// @Success 200 {object} dtos.UserListResponse "Successfully retrieved users"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid query parameter"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/users [get]
+func ListUsers(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid page number"})
+		return
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize <= 0 || pageSize > 100 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid pageSize number"})
+		return
+	}
+
+	query := db.DB.Model(&models.User{})
+
+	if email := c.Query("email"); email != "" {
+		query = query.Where("LOWER(email) LIKE ?", "%"+strings.ToLower(email)+"%")
+	}
+	if name := c.Query("name"); name != "" {
+		pattern := "%" + strings.ToLower(name) + "%"
+		query = query.Where("LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?", pattern, pattern)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	switch c.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("suspended_at IS NULL")
+	case "suspended":
+		query = query.Where("suspended_at IS NOT NULL")
+	default:
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid status, expected active or suspended"})
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "-created_at")
+	direction := "ASC"
+	if strings.HasPrefix(sort, "-") {
+		sort, direction = sort[1:], "DESC"
+	}
+
+	column, ok := userSortColumns[sort]
+	if !ok {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Invalid sort key: %s", sort)})
+		return
+	}
+
+	var totalUsers int64
+	if err := query.Count(&totalUsers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve users"})
+		return
+	}
+
+	users := []models.User{}
+	result := query.
+		Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Limit(pageSize).Offset((page - 1) * pageSize).
+		Find(&users)
+	if result.Error != nil {
+		log.Printf("Failed to retrieve users: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.UserListResponse{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalUsers,
+		TotalPages: int64(math.Ceil(float64(totalUsers) / float64(pageSize))),
+		Users:      users,
+	})
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Description Allows an admin to view a user with their assigned roles and order counts by status.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} dtos.UserDetailResponse "Successfully retrieved user"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "User not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/users/{id} [get]
+func GetUser(c *gin.Context) {
+	user, ok := findUserByParam(c, db.DB.Preload("Roles"))
+	if !ok {
+		return
+	}
+
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	err := db.DB.Model(&models.Order{}).
+		Select("status, COUNT(*) AS count").
+		Where("user_id = ?", user.ID).
+		Group("status").
+		Scan(&counts).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve order counts"})
+		return
+	}
+
+	response := dtos.UserDetailResponse{User: user, OrderCounts: make(map[string]int64)}
+	for _, count := range counts {
+		response.OrderCounts[count.Status] = count.Count
+		response.OrderCount += count.Count
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SuspendUser godoc
+// @Summary Suspend a user
+// @Description Allows an admin to suspend an account. Suspended users cannot log in, and their sessions and API keys stop working until the account is reactivated. Only users whose permissions the admin also has can be suspended.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param input body dtos.SuspendUserRequest false "Suspension reason"
+// @Success 200 {object} models.User "User suspended successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID or user already suspended"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required, or the user has permissions the admin lacks"
+// @Failure 404 {object} dtos.ErrorResponse "User not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/users/{id}/suspend [patch]
+func SuspendUser(c *gin.Context) {
+	var req dtos.SuspendUserRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleValidationErrors(err, c)
+			return
+		}
+	}
+
+	user, ok := findUserByParam(c, db.DB)
+	if !ok {
+		return
+	}
+
+	authUser, _ := c.Get("user")
+	actor := authUser.(models.User)
+	if actor.ID == user.ID {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "You cannot suspend your own account"})
+		return
+	}
+
+	// Suspending a user takes away their permissions, which only someone holding all of
+	// them may do
+	if err := user.LoadPermissions(db.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to suspend user"})
+		return
+	}
+	for _, permission := range user.Permissions {
+		if !actor.HasPermission(permission) {
+			c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "You cannot suspend a user with permissions you do not have"})
+			return
+		}
+	}
+
+	if user.IsSuspended() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "User is already suspended"})
+		return
+	}
+
+	now := time.Now()
+	user.SuspendedAt = &now
+	user.SuspensionReason = req.Reason
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&user).Updates(map[string]interface{}{"suspended_at": now, "suspension_reason": req.Reason}).Error
+		if err != nil {
+			return err
+		}
+
+		return revokeUserSessions(tx, user.ID)
+	})
+	if err != nil {
+		log.Printf("Failed to suspend user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to suspend user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ReactivateUser godoc
+// @Summary Reactivate a user
+// @Description Allows an admin to lift a suspension so that the user can log in again.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.User "User reactivated successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID or user not suspended"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "User not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/users/{id}/reactivate [patch]
+func ReactivateUser(c *gin.Context) {
+	user, ok := findUserByParam(c, db.DB)
+	if !ok {
+		return
+	}
+
+	if !user.IsSuspended() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "User is not suspended"})
+		return
+	}
+
+	user.SuspendedAt = nil
+	user.SuspensionReason = ""
+
+	err := db.DB.Model(&user).Updates(map[string]interface{}{"suspended_at": nil, "suspension_reason": ""}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to reactivate user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// findUserByParam loads the user named by the :id path parameter through query,
+// writing an error response if there is none.
+func findUserByParam(c *gin.Context, query *gorm.DB) (models.User, bool) {
+	var user models.User
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid user ID"})
+		return user, false
+	}
+
+	result := query.First(&user, userID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return user, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAdminUserManagement(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.Session{},
+		&models.Order{}, &models.Address{}, &models.LoginThrottle{}, &models.AccountLockout{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: string(hashedPassword)}
+	mockDB.Create(&admin)
+
+	alice := models.User{Email: "alice@example.com", FirstName: "Alice", LastName: "Smith", Role: "customer", Password: string(hashedPassword), LastLogin: time.Now()}
+	mockDB.Create(&alice)
+
+	bob := models.User{Email: "bob@shop.test", FirstName: "Bob", LastName: "Jones", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&bob)
+
+	mockDB.Create(&models.Order{UserID: alice.ID, Total: 10, Status: models.OrderStatusPending})
+	mockDB.Create(&models.Order{UserID: alice.ID, Total: 20, Status: models.OrderStatusCompleted})
+	mockDB.Create(&models.Order{UserID: alice.ID, Total: 30, Status: models.OrderStatusCompleted})
+
+	adminTokens, _ := issueTokens(newTestContext(), &admin, "")
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(middleware.LoadAuthUserMiddleware())
+	router.POST("/login", LoginUser)
+
+	userAdmin := router.Group("/admin", middleware.RequirePermission(models.PermissionUsersManage))
+	userAdmin.GET("/users", ListUsers)
+	userAdmin.GET("/users/:id", GetUser)
+	userAdmin.PATCH("/users/:id/suspend", SuspendUser)
+	userAdmin.PATCH("/users/:id/reactivate", ReactivateUser)
+
+	request := func(method, path, accessToken string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	listEmails := func(query string) []string {
+		rec := request("GET", "/admin/users?"+query, adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.UserListResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+
+		emails := []string{}
+		for _, user := range response.Users {
+			emails = append(emails, user.Email)
+		}
+		return emails
+	}
+
+	t.Run("Lists users with filters, sorting and pagination", func(t *testing.T) {
+		assert.Equal(t, []string{"admin@example.com", "alice@example.com", "bob@shop.test"}, listEmails("sort=email"))
+		assert.Equal(t, []string{"bob@shop.test", "alice@example.com"}, listEmails("role=customer&sort=-email"))
+		assert.Equal(t, []string{"alice@example.com"}, listEmails("name=SMI"))
+		assert.Equal(t, []string{"bob@shop.test"}, listEmails("email=shop"))
+		assert.Equal(t, []string{"alice@example.com"}, listEmails("sort=email&page=2&pageSize=1"))
+
+		rec := request("GET", "/admin/users?sort=password", adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Shows a user's order counts", func(t *testing.T) {
+		rec := request("GET", fmt.Sprintf("/admin/users/%d", alice.ID), adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.UserDetailResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.Equal(t, alice.Email, response.User.Email)
+		assert.Equal(t, int64(3), response.OrderCount)
+		assert.Equal(t, int64(2), response.OrderCounts[models.OrderStatusCompleted])
+		assert.False(t, response.User.LastLogin.IsZero())
+
+		rec = request("GET", "/admin/users/999", adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Suspends and reactivates a user", func(t *testing.T) {
+		bobTokens, _ := issueTokens(newTestContext(), &bob, "")
+
+		rec := request("PATCH", fmt.Sprintf("/admin/users/%d/suspend", bob.ID), adminTokens.AccessToken, dtos.SuspendUserRequest{Reason: "Fraud"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		assert.Equal(t, []string{"bob@shop.test"}, listEmails("status=suspended"))
+
+		rec = request("GET", "/admin/users", bobTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("POST", "/login", "", dtos.LoginRequest{Email: bob.Email, Password: "password"})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = request("PATCH", fmt.Sprintf("/admin/users/%d/reactivate", bob.ID), adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = request("POST", "/login", "", dtos.LoginRequest{Email: bob.Email, Password: "password"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Cannot suspend yourself", func(t *testing.T) {
+		rec := request("PATCH", fmt.Sprintf("/admin/users/%d/suspend", admin.ID), adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Cannot suspend users with permissions the admin lacks", func(t *testing.T) {
+		permission := models.Permission{Name: models.PermissionUsersManage}
+		mockDB.Create(&permission)
+		role := models.Role{Name: "support", Permissions: []models.Permission{permission}}
+		mockDB.Create(&role)
+
+		staff := models.User{Email: "staff@example.com", FirstName: "Staff", LastName: "User", Role: "customer", Password: string(hashedPassword)}
+		mockDB.Create(&staff)
+		mockDB.Model(&staff).Association("Roles").Append(&role)
+		staffTokens, _ := issueTokens(newTestContext(), &staff, "")
+
+		rec := request("PATCH", fmt.Sprintf("/admin/users/%d/suspend", admin.ID), staffTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		var unchanged models.User
+		mockDB.First(&unchanged, admin.ID)
+		assert.Nil(t, unchanged.SuspendedAt)
+
+		rec = request("PATCH", fmt.Sprintf("/admin/users/%d/suspend", alice.ID), staffTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = request("PATCH", fmt.Sprintf("/admin/users/%d/reactivate", alice.ID), adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Keeps the user active when sessions cannot be revoked", func(t *testing.T) {
+		mockDB.Callback().Update().Before("gorm:update").Register("test:fail_sessions", func(tx *gorm.DB) {
+			if tx.Statement.Table == "sessions" {
+				tx.AddError(errors.New("sessions unavailable"))
+			}
+		})
+		defer mockDB.Callback().Update().Remove("test:fail_sessions")
+
+		rec := request("PATCH", fmt.Sprintf("/admin/users/%d/suspend", bob.ID), adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		var unchanged models.User
+		mockDB.First(&unchanged, bob.ID)
+		assert.Nil(t, unchanged.SuspendedAt)
+	})
+}
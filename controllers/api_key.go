@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Creates a named API key for server-to-server integrations, sent in the X-API-Key header. The key can only be scoped to permissions the user has, and is only returned once.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param input body dtos.CreateAPIKeyRequest true "API key details"
+// @Success 201 {object} dtos.APIKeyCreatedResponse "API key created successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid input data or unknown permission"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Scope exceeds the user's permissions, or request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	scopes, err := findPermissions(req.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	for _, scope := range scopes {
+		if !user.HasPermission(scope.Name) {
+			c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "You do not have the " + scope.Name + " permission"})
+			return
+		}
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to generate API key"})
+		return
+	}
+	key := models.APIKeyPrefix + secret
+
+	apiKey := models.APIKey{
+		Name:    req.Name,
+		Prefix:  key[:len(models.APIKeyPrefix)+6],
+		KeyHash: utils.HashToken(key),
+		UserID:  user.ID,
+		Scopes:  scopes,
+	}
+
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := db.DB.Create(&apiKey).Error; err != nil {
+		log.Printf("Failed to create API key: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to create API key"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.APIKeyCreatedResponse{APIKey: apiKey, Key: key})
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Retrieve the logged in user's API keys, including revoked and expired ones.
+// @Tags API Keys
+// @Produce json
+// @Success 200 {array} models.APIKey "Successfully retrieved API keys"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/api-keys [get]
+func ListAPIKeys(c *gin.Context) {
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	var apiKeys []models.APIKey
+	if err := db.DB.Preload("Scopes").Where("user_id = ?", user.ID).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke one of the logged in user's API keys so that it can no longer be used.
+// @Tags API Keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} models.APIKey "API key revoked successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid API key ID or key already revoked"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 404 {object} dtos.ErrorResponse "API key not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/api-keys/{id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || keyID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid API key ID"})
+		return
+	}
+
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	var apiKey models.APIKey
+	result := db.DB.Preload("Scopes").Where("user_id = ?", user.ID).First(&apiKey, keyID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "API key not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return
+	}
+
+	if apiKey.RevokedAt != nil {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "API key is already revoked"})
+		return
+	}
+
+	now := time.Now()
+	apiKey.RevokedAt = &now
+	if err := db.DB.Model(&apiKey).Update("revoked_at", now).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKey)
+}
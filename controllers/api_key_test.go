@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAPIKeys(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.RevokedToken{}, &models.Session{}, &models.APIKey{}, &models.Category{}, &models.Product{}, &models.ProductImage{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	db.SeedRolesAndPermissions()
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: "password"}
+	mockDB.Create(&admin)
+
+	customer := models.User{Email: "customer@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: "password"}
+	mockDB.Create(&customer)
+
+	category := models.Category{Name: "Clothing", Slug: "clothing"}
+	mockDB.Create(&category)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(middleware.LoadAuthUserMiddleware())
+	router.POST("/users/api-keys", CreateAPIKey)
+	router.GET("/users/api-keys", ListAPIKeys)
+	router.DELETE("/users/api-keys/:id", RevokeAPIKey)
+	router.POST("/products", CreateProduct)
+	router.DELETE("/products/:id", DeleteProduct)
+	router.POST("/orders", CreateOrder)
+	router.GET("/orders/:user_id", ListOrders)
+	router.PATCH("/orders/:id/cancel", CancelOrder)
+	router.GET("/users/addresses", ListAddresses)
+	router.POST("/users/addresses", CreateAddress)
+	router.GET("/users/addresses/:id", GetAddress)
+	router.DELETE("/users/addresses/:id", DeleteAddress)
+	router.GET("/users/me", GetProfile)
+	router.GET("/users/sessions", ListSessions)
+	router.POST("/verify-email/resend", ResendVerificationEmail)
+
+	bearer := func(user models.User) http.Header {
+		tokens, _ := models.GenerateJwtTokens(&user, "")
+		return http.Header{"Authorization": []string{"Bearer " + tokens.AccessToken}}
+	}
+
+	apiKey := func(key string) http.Header {
+		return http.Header{"X-Api-Key": []string{key}}
+	}
+
+	request := func(method, path string, header http.Header, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header = header
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var created dtos.APIKeyCreatedResponse
+
+	t.Run("Creates a scoped API key", func(t *testing.T) {
+		rec := request("POST", "/users/api-keys", bearer(admin), dtos.CreateAPIKeyRequest{
+			Name:   "ERP product sync",
+			Scopes: []string{models.PermissionProductsCreate},
+		})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		assert.Contains(t, created.Key, models.APIKeyPrefix)
+		assert.Equal(t, created.Key[:len(created.APIKey.Prefix)], created.APIKey.Prefix)
+		assert.Len(t, created.APIKey.Scopes, 1)
+
+		var stored models.APIKey
+		mockDB.First(&stored, created.APIKey.ID)
+		assert.NotEqual(t, created.Key, stored.KeyHash)
+	})
+
+	t.Run("Cannot grant permissions the owner lacks", func(t *testing.T) {
+		rec := request("POST", "/users/api-keys", bearer(customer), dtos.CreateAPIKeyRequest{
+			Name:   "Sneaky",
+			Scopes: []string{models.PermissionProductsDelete},
+		})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Authenticates with the key within its scopes", func(t *testing.T) {
+		rec := request("POST", "/products", apiKey(created.Key), dtos.CreateProductRequest{
+			Name: "Shirt", Description: "Cotton", Price: 10, Stock: 5, CategoryID: category.ID,
+		})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var product models.Product
+		json.Unmarshal(rec.Body.Bytes(), &product)
+
+		rec = request("DELETE", fmt.Sprintf("/products/%d", product.ID), apiKey(created.Key), nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		var stored models.APIKey
+		mockDB.First(&stored, created.APIKey.ID)
+		assert.NotNil(t, stored.LastUsedAt)
+	})
+
+	t.Run("Cannot manage API keys with an API key", func(t *testing.T) {
+		rec := request("GET", "/users/api-keys", apiKey(created.Key), nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Cannot act on the owner's account with a scoped key", func(t *testing.T) {
+		endpoints := []struct{ method, path string }{
+			{"POST", "/orders"},
+			{"GET", fmt.Sprintf("/orders/%d", admin.ID)},
+			{"PATCH", "/orders/1/cancel"},
+			{"GET", "/users/addresses"},
+			{"POST", "/users/addresses"},
+			{"GET", "/users/addresses/1"},
+			{"DELETE", "/users/addresses/1"},
+			{"GET", "/users/me"},
+			{"GET", "/users/sessions"},
+			{"POST", "/verify-email/resend"},
+		}
+		for _, endpoint := range endpoints {
+			rec := request(endpoint.method, endpoint.path, apiKey(created.Key), nil)
+			assert.Equal(t, http.StatusForbidden, rec.Code, endpoint.method+" "+endpoint.path)
+		}
+	})
+
+	t.Run("Rejects revoked and unknown keys", func(t *testing.T) {
+		rec := request("DELETE", fmt.Sprintf("/users/api-keys/%d", created.APIKey.ID), bearer(customer), nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = request("DELETE", fmt.Sprintf("/users/api-keys/%d", created.APIKey.ID), bearer(admin), nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = request("POST", "/products", apiKey(created.Key), dtos.CreateProductRequest{
+			Name: "Shirt", Description: "Cotton", Price: 10, Stock: 5, CategoryID: category.ID,
+		})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("POST", "/products", apiKey(models.APIKeyPrefix+"unknown"), dtos.CreateProductRequest{
+			Name: "Shirt", Description: "Cotton", Price: 10, Stock: 5, CategoryID: category.ID,
+		})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
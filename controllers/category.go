@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ListCategories godoc
+// @Summary List categories
+// @Description Retrieve the category tree. Top-level categories are listed with their descendants nested under children, each level sorted by name.
+// @Tags Category
+// @Produce json
+// @Success 200 {array} models.Category "Successfully retrieved categories"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /categories [get]
+func ListCategories(c *gin.Context) {
+	var categories []models.Category
+	if err := db.DB.Order("name").Find(&categories).Error; err != nil {
+		log.Printf("Failed to retrieve categories: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, categoryTree(categories))
+}
+
+// GetCategory godoc
+// @Summary Get a category
+// @Description Retrieve a category by its slug, with its direct subcategories.
+// @Tags Category
+// @Produce json
+// @Param slug path string true "Category slug"
+// @Success 200 {object} models.Category "Successfully retrieved category"
+// @Failure 404 {object} dtos.ErrorResponse "Category not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /categories/{slug} [get]
+func GetCategory(c *gin.Context) {
+	category, ok := findCategoryBySlug(c)
+	if !ok {
+		return
+	}
+
+	err := db.DB.Model(&category).Order("name").Association("Children").Find(&category.Children)
+	if err != nil {
+		log.Printf("Failed to retrieve subcategories of category %v: %v", category.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// ListCategoryProducts godoc
+// @Summary List the products in a category
+// @Description Retrieve a paginated list of the products in a category and all of its descendants. Accepts the same filters, sorting and field selection as listing all products.
+// @Tags Category
+// @Produce json
+// @Param slug path string true "Category slug"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Number of products per page, lowered to 100 if larger" default(10)
+// @Param min_price query number false "Minimum price, which a product with variants meets if any variant does"
+// @Param max_price query number false "Maximum price, which a product with variants meets if any variant does"
+// @Param in_stock query bool false "Only products in stock, or with a variant in stock"
+// @Param created_after query string false "Only products created after this RFC 3339 timestamp or YYYY-MM-DD date"
+// @Param sort query string false "Comma-separated sort keys, each prefixed with - for descending order. Keys: id, name, price, stock, created_at. Products with variants sort by their cheapest variant and the total stock of their variants" default(id)
+// @Param fields query string false "Comma-separated fields to return. Fields: id, name, category_id, category, description, price, stock, images, created_at, updated_at"
+// @Success 200 {object} dtos.CategoryProductListResponse "Successfully retrieved the products"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid query parameter"
+// @Failure 404 {object} dtos.ErrorResponse "Category not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /categories/{slug}/products [get]
+func ListCategoryProducts(c *gin.Context) {
+	category, ok := findCategoryBySlug(c)
+	if !ok {
+		return
+	}
+
+	categoryIDs, err := categorySubtreeIDs("id = ?", category.ID)
+	if err != nil {
+		log.Printf("Failed to retrieve subcategories of category %v: %v", category.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve products"})
+		return
+	}
+
+	response, ok := listProducts(c, db.DB.Model(&models.Product{}).Where("category_id IN ?", categoryIDs))
+	if !ok {
+		return
+	}
+
+	response["category"] = category
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateCategory godoc
+// @Summary Create a category
+// @Description Create a category, optionally under a parent category.
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Param input body dtos.CategoryRequest true "Category information"
+// @Success 201 {object} models.Category "Category created successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid input data, slug already in use or unknown parent"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The categories:manage permission is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /categories [post]
+func CreateCategory(c *gin.Context) {
+	var req dtos.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	var category models.Category
+	if !applyCategoryRequest(c, &category, req) {
+		return
+	}
+
+	if err := db.DB.Create(&category).Error; err != nil {
+		log.Printf("Failed to create category: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to create category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Update a category
+// @Description Replace the name, slug, description and parent of a category. A category cannot be moved under itself or one of its descendants.
+// @Tags Category
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param input body dtos.CategoryRequest true "Category information"
+// @Success 200 {object} models.Category "Category updated successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid input data, slug already in use, unknown parent or parent inside the category"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The categories:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "Category not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /categories/{id} [put]
+func UpdateCategory(c *gin.Context) {
+	category, ok := findCategoryByID(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	if req.ParentID != nil {
+		subtree, err := categorySubtreeIDs("id = ?", category.ID)
+		if err != nil {
+			log.Printf("Failed to retrieve subcategories of category %v: %v", category.ID, err)
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update category"})
+			return
+		}
+		if slices.Contains(subtree, *req.ParentID) {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "A category cannot be moved under itself or one of its subcategories"})
+			return
+		}
+	}
+
+	if !applyCategoryRequest(c, &category, req) {
+		return
+	}
+
+	if err := db.DB.Save(&category).Error; err != nil {
+		log.Printf("Failed to update category %v: %v", category.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category
+// @Description Delete a category. Categories that still have subcategories or products cannot be deleted; move or delete those first.
+// @Tags Category
+// @Param id path int true "Category ID"
+// @Success 204 "Category deleted successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid category ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The categories:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "Category not found"
+// @Failure 409 {object} dtos.ErrorResponse "Category still has subcategories or products"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /categories/{id} [delete]
+func DeleteCategory(c *gin.Context) {
+	category, ok := findCategoryByID(c)
+	if !ok {
+		return
+	}
+
+	var children, products int64
+	if err := db.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to delete category"})
+		return
+	}
+	if err := db.DB.Model(&models.Product{}).Where("category_id = ?", category.ID).Count(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to delete category"})
+		return
+	}
+	if children > 0 || products > 0 {
+		c.JSON(http.StatusConflict, dtos.ErrorResponse{
+			Error: fmt.Sprintf("Category still has %d subcategories and %d products", children, products),
+		})
+		return
+	}
+
+	if err := db.DB.Delete(&category).Error; err != nil {
+		log.Printf("Failed to delete category %v: %v", category.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to delete category"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// applyCategoryRequest copies req onto category, deriving the slug from the name if
+// none is given, and writes an error response if the slug is taken by another category
+// or the parent does not exist.
+func applyCategoryRequest(c *gin.Context, category *models.Category, req dtos.CategoryRequest) bool {
+	slug := req.Slug
+	if slug == "" {
+		slug = models.Slugify(req.Name)
+	}
+	if !categorySlugPattern.MatchString(slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"slug": "Value must be lowercase letters and digits separated by hyphens.",
+		}})
+		return false
+	}
+
+	var existing int64
+	db.DB.Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, category.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Category with this slug already exists"})
+		return false
+	}
+
+	if req.ParentID != nil {
+		var parents int64
+		db.DB.Model(&models.Category{}).Where("id = ?", *req.ParentID).Count(&parents)
+		if parents == 0 {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Invalid parent category ID: %d", *req.ParentID)})
+			return false
+		}
+	}
+
+	category.Name = req.Name
+	category.Slug = slug
+	category.Description = req.Description
+	category.ParentID = req.ParentID
+	return true
+}
+
+// findCategoryBySlug loads the category named by the :slug path parameter, writing an
+// error response if there is none.
+func findCategoryBySlug(c *gin.Context) (models.Category, bool) {
+	var category models.Category
+	if err := db.DB.Where("slug = ?", c.Param("slug")).Limit(1).Find(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve category"})
+		return category, false
+	}
+	if category.ID == 0 {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Category not found"})
+		return category, false
+	}
+
+	return category, true
+}
+
+// findCategoryByID loads the category named by the :id path parameter, writing an
+// error response if the ID is invalid or there is no such category.
+func findCategoryByID(c *gin.Context) (models.Category, bool) {
+	var category models.Category
+
+	categoryID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || categoryID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid category ID"})
+		return category, false
+	}
+
+	if err := db.DB.Limit(1).Find(&category, categoryID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve category"})
+		return category, false
+	}
+	if category.ID == 0 {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Category not found"})
+		return category, false
+	}
+
+	return category, true
+}
+
+// categorySubtreeIDs returns the IDs of the category matching condition and of all of
+// its descendants.
+func categorySubtreeIDs(condition string, value interface{}) ([]uint, error) {
+	ids := []uint{}
+	err := db.DB.Raw(`WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM categories WHERE `+condition+`
+			UNION
+			SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id
+		)
+		SELECT id FROM subtree`, value).Scan(&ids).Error
+	return ids, err
+}
+
+// parseCategoryParam turns a category slug into the IDs of the category and its
+// descendants, so that filtering by a category includes its subcategories. Unknown
+// slugs match no products.
+func parseCategoryParam(slug string) (interface{}, error) {
+	return categorySubtreeIDs("slug = ?", slug)
+}
+
+// categoryTree nests categories under their parents and returns the top-level ones,
+// keeping the order of categories within each level.
+func categoryTree(categories []models.Category) []models.Category {
+	children := map[uint][]models.Category{}
+	for _, category := range categories {
+		var parentID uint
+		if category.ParentID != nil {
+			parentID = *category.ParentID
+		}
+		children[parentID] = append(children[parentID], category)
+	}
+
+	var build func(parentID uint) []models.Category
+	build = func(parentID uint) []models.Category {
+		level := children[parentID]
+		for i := range level {
+			level[i].Children = build(level[i].ID)
+		}
+		return level
+	}
+
+	if roots := build(0); roots != nil {
+		return roots
+	}
+	return []models.Category{}
+}
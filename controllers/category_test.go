@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCategories(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.Category{}, &models.Product{}, &models.ProductVariant{}, &models.ProductImage{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	db.SeedRolesAndPermissions()
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: "password"}
+	mockDB.Create(&admin)
+	admin.LoadPermissions(mockDB)
+
+	customer := models.User{Email: "customer@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: "password"}
+	mockDB.Create(&customer)
+
+	newRouter := func(user models.User) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+		})
+
+		router.GET("/categories", ListCategories)
+		router.GET("/categories/:slug", GetCategory)
+		router.GET("/categories/:slug/products", ListCategoryProducts)
+
+		categoryAdmin := router.Group("/categories", middleware.RequirePermission(models.PermissionCategoriesManage))
+		categoryAdmin.POST("", CreateCategory)
+		categoryAdmin.PUT("/:id", UpdateCategory)
+		categoryAdmin.DELETE("/:id", DeleteCategory)
+		return router
+	}
+
+	router := newRouter(admin)
+
+	request := func(router *gin.Engine, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	create := func(req dtos.CategoryRequest) models.Category {
+		rec := request(router, "POST", "/categories", req)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var category models.Category
+		json.Unmarshal(rec.Body.Bytes(), &category)
+		return category
+	}
+
+	clothing := create(dtos.CategoryRequest{Name: "Clothing"})
+	shirts := create(dtos.CategoryRequest{Name: "Men's Shirts", ParentID: &clothing.ID})
+	tees := create(dtos.CategoryRequest{Name: "T-Shirts", Slug: "tees", ParentID: &shirts.ID})
+	home := create(dtos.CategoryRequest{Name: "Home"})
+
+	for _, product := range []models.Product{
+		{Name: "Jacket", CategoryID: clothing.ID, Price: 80, Stock: 1},
+		{Name: "Oxford Shirt", CategoryID: shirts.ID, Price: 40, Stock: 1},
+		{Name: "Plain Tee", CategoryID: tees.ID, Price: 10, Stock: 0},
+		{Name: "Lamp", CategoryID: home.ID, Price: 20, Stock: 1},
+	} {
+		mockDB.Create(&product)
+	}
+
+	t.Run("Lists categories as a tree", func(t *testing.T) {
+		assert.Equal(t, "mens-shirts", shirts.Slug)
+
+		rec := request(router, "GET", "/categories", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var categories []models.Category
+		json.Unmarshal(rec.Body.Bytes(), &categories)
+		assert.Len(t, categories, 2)
+		assert.Equal(t, "Clothing", categories[0].Name)
+		assert.Equal(t, "Home", categories[1].Name)
+		assert.Equal(t, "tees", categories[0].Children[0].Children[0].Slug)
+
+		rec = request(router, "GET", "/categories/mens-shirts", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var category models.Category
+		json.Unmarshal(rec.Body.Bytes(), &category)
+		assert.Equal(t, shirts.ID, category.ID)
+		assert.Len(t, category.Children, 1)
+
+		rec = request(router, "GET", "/categories/unknown", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Lists the products of a category and its descendants", func(t *testing.T) {
+		rec := request(router, "GET", "/categories/clothing/products?sort=name", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.CategoryProductListResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.Equal(t, clothing.ID, response.Category.ID)
+		assert.Equal(t, int64(3), response.TotalCount)
+
+		var names []string
+		for _, product := range response.Products {
+			names = append(names, product.Name)
+		}
+		assert.Equal(t, []string{"Jacket", "Oxford Shirt", "Plain Tee"}, names)
+		assert.Equal(t, "Clothing", response.Products[0].Category.Name)
+
+		rec = request(router, "GET", "/categories/mens-shirts/products?in_stock=true", nil)
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.Equal(t, int64(1), response.TotalCount)
+		assert.Equal(t, "Oxford Shirt", response.Products[0].Name)
+	})
+
+	t.Run("Rejects duplicate and invalid slugs and unknown parents", func(t *testing.T) {
+		rec := request(router, "POST", "/categories", dtos.CategoryRequest{Name: "Clothing"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = request(router, "POST", "/categories", dtos.CategoryRequest{Name: "Shoes", Slug: "Shoes!"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"slug"`)
+
+		missing := uint(999)
+		rec = request(router, "POST", "/categories", dtos.CategoryRequest{Name: "Shoes", ParentID: &missing})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Cannot move a category under its own descendant", func(t *testing.T) {
+		rec := request(router, "PUT", fmt.Sprintf("/categories/%d", clothing.ID), dtos.CategoryRequest{Name: "Clothing", ParentID: &tees.ID})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = request(router, "PUT", fmt.Sprintf("/categories/%d", tees.ID), dtos.CategoryRequest{Name: "Tees", Slug: "tees", ParentID: &clothing.ID})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var category models.Category
+		mockDB.First(&category, tees.ID)
+		assert.Equal(t, "Tees", category.Name)
+		assert.Equal(t, clothing.ID, *category.ParentID)
+	})
+
+	t.Run("Only deletes empty categories", func(t *testing.T) {
+		rec := request(router, "DELETE", fmt.Sprintf("/categories/%d", clothing.ID), nil)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = request(router, "DELETE", fmt.Sprintf("/categories/%d", home.ID), nil)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		mockDB.Where("category_id = ?", home.ID).Delete(&models.Product{}, &models.ProductImage{})
+		rec = request(router, "DELETE", fmt.Sprintf("/categories/%d", home.ID), nil)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("Requires the categories:manage permission", func(t *testing.T) {
+		rec := request(newRouter(customer), "POST", "/categories", dtos.CategoryRequest{Name: "Garden"})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonateUser godoc
+// @Summary Impersonate a customer
+// @Description Issues a short-lived access token that lets a support admin act as a customer to reproduce their issue. The token names the admin in an "act" claim, cannot be refreshed, cannot be used to change the customer's credentials, and every request made with it is recorded in the impersonation audit log.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param input body dtos.ImpersonateUserRequest true "Reason for the impersonation"
+// @Success 200 {object} dtos.ImpersonationResponse "Impersonation token issued"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID or user cannot be impersonated"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:impersonate permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "User not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/users/{id}/impersonate [post]
+func ImpersonateUser(c *gin.Context) {
+	var req dtos.ImpersonateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	actor, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	user, ok := findUserByParam(c, db.DB)
+	if !ok {
+		return
+	}
+
+	if user.ID == actor.ID {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "You cannot impersonate yourself"})
+		return
+	}
+
+	if user.IsAnonymized() {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	if user.IsSuspended() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Suspended users cannot be impersonated"})
+		return
+	}
+
+	// Only customers may be impersonated, so an impersonation token never carries
+	// privileges of its own.
+	if err := user.LoadPermissions(db.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to load user permissions"})
+		return
+	}
+	if user.IsAdmin() || len(user.Permissions) > 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Only customers can be impersonated"})
+		return
+	}
+
+	token, expiresAt, err := models.GenerateImpersonationToken(&user, &actor)
+	if err != nil {
+		log.Printf("Failed to generate impersonation token: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to generate impersonation token"})
+		return
+	}
+
+	entry := models.ImpersonationAudit{
+		ActorID:   actor.ID,
+		UserID:    user.ID,
+		Action:    models.ImpersonationActionStart,
+		Reason:    req.Reason,
+		IPAddress: c.ClientIP(),
+	}
+	if err := db.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to record impersonation"})
+		return
+	}
+
+	log.Printf("Impersonation: admin %d started impersonating user %d: %s", actor.ID, user.ID, req.Reason)
+
+	user.Permissions = nil
+	c.JSON(http.StatusOK, dtos.ImpersonationResponse{AccessToken: token, ExpiresAt: expiresAt, User: user})
+}
+
+// ListImpersonationAudits godoc
+// @Summary List impersonation audit entries
+// @Description Allows an admin to review when admins impersonated users and every request they made while doing so, newest first.
+// @Tags Admin
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Number of entries per page" default(10)
+// @Param actor_id query int false "Filter by impersonating admin"
+// @Param user_id query int false "Filter by impersonated user"
+// @Success 200 {object} dtos.ImpersonationAuditListResponse "Successfully retrieved audit entries"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid query parameter"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/impersonations [get]
+func ListImpersonationAudits(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid page number"})
+		return
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize <= 0 || pageSize > 100 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid pageSize number"})
+		return
+	}
+
+	query := db.DB.Model(&models.ImpersonationAudit{})
+
+	for _, filter := range []string{"actor_id", "user_id"} {
+		value := c.Query(filter)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid " + filter})
+			return
+		}
+		query = query.Where(filter+" = ?", id)
+	}
+
+	var totalEntries int64
+	if err := query.Count(&totalEntries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve audit entries"})
+		return
+	}
+
+	entries := []models.ImpersonationAudit{}
+	result := query.Order("id DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&entries)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve audit entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.ImpersonationAuditListResponse{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalEntries,
+		TotalPages: int64(math.Ceil(float64(totalEntries) / float64(pageSize))),
+		Entries:    entries,
+	})
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestImpersonation(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.Session{},
+		&models.LoginThrottle{}, &models.AccountLockout{}, &models.ImpersonationAudit{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: string(hashedPassword)}
+	mockDB.Create(&admin)
+
+	otherAdmin := models.User{Email: "other-admin@example.com", FirstName: "Other", LastName: "Admin", Role: "admin", Password: string(hashedPassword)}
+	mockDB.Create(&otherAdmin)
+
+	customer := models.User{Email: "customer@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&customer)
+
+	adminTokens, _ := issueTokens(newTestContext(), &admin, "")
+	customerTokens, _ := issueTokens(newTestContext(), &customer, "")
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(middleware.LoadAuthUserMiddleware())
+	router.GET("/users/me", GetProfile)
+	router.POST("/users/me/password", ChangePassword)
+	router.GET("/admin/impersonations", middleware.RequirePermission(models.PermissionUsersManage), ListImpersonationAudits)
+	router.POST("/admin/users/:id/impersonate", middleware.RequirePermission(models.PermissionUsersImpersonate), ImpersonateUser)
+
+	request := func(method, path, accessToken string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	impersonate := func(accessToken string, userID uint) *httptest.ResponseRecorder {
+		return request("POST", fmt.Sprintf("/admin/users/%d/impersonate", userID), accessToken, dtos.ImpersonateUserRequest{Reason: "Ticket #1"})
+	}
+
+	var impersonationToken string
+
+	t.Run("Issues a short-lived token marked with the admin", func(t *testing.T) {
+		rec := impersonate(adminTokens.AccessToken, customer.ID)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.ImpersonationResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.Equal(t, customer.Email, response.User.Email)
+		assert.WithinDuration(t, time.Now().Add(models.ImpersonationTokenLifetime), response.ExpiresAt, time.Minute)
+		impersonationToken = response.AccessToken
+
+		claims, err := models.ParseJwtToken(impersonationToken)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(customer.ID), claims["userID"])
+		assert.Equal(t, float64(admin.ID), claims["act"].(map[string]interface{})["userID"])
+
+		var start models.ImpersonationAudit
+		mockDB.Where("action = ?", models.ImpersonationActionStart).First(&start)
+		assert.Equal(t, admin.ID, start.ActorID)
+		assert.Equal(t, customer.ID, start.UserID)
+		assert.Equal(t, "Ticket #1", start.Reason)
+	})
+
+	t.Run("Acts as the customer and audits every request", func(t *testing.T) {
+		rec := request("GET", "/users/me", impersonationToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var profile models.User
+		json.Unmarshal(rec.Body.Bytes(), &profile)
+		assert.Equal(t, customer.Email, profile.Email)
+
+		var entries []models.ImpersonationAudit
+		mockDB.Where("action = ?", models.ImpersonationActionRequest).Find(&entries)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, admin.ID, entries[0].ActorID)
+		assert.Equal(t, "/users/me", entries[0].Path)
+		assert.Equal(t, http.StatusOK, entries[0].StatusCode)
+	})
+
+	t.Run("Blocks sensitive actions while impersonating", func(t *testing.T) {
+		rec := request("POST", "/users/me/password", impersonationToken,
+			dtos.ChangePasswordRequest{CurrentPassword: "password", Password: "newpassword", PasswordConfirm: "newpassword"})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		var updated models.User
+		mockDB.First(&updated, customer.ID)
+		assert.NoError(t, updated.IsValidPassword("password"))
+
+		var blocked models.ImpersonationAudit
+		mockDB.Where("path = ?", "/users/me/password").First(&blocked)
+		assert.Equal(t, http.StatusForbidden, blocked.StatusCode)
+	})
+
+	t.Run("Only customers can be impersonated, by admins", func(t *testing.T) {
+		rec := impersonate(customerTokens.AccessToken, admin.ID)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = impersonate(adminTokens.AccessToken, otherAdmin.ID)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = impersonate(adminTokens.AccessToken, admin.ID)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = impersonate(impersonationToken, customer.ID)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Lists audit entries", func(t *testing.T) {
+		rec := request("GET", fmt.Sprintf("/admin/impersonations?user_id=%d", customer.ID), adminTokens.AccessToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.ImpersonationAuditListResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.Equal(t, int64(4), response.TotalCount)
+		assert.Equal(t, models.ImpersonationActionRequest, response.Entries[0].Action)
+	})
+
+	t.Run("Ends when the admin is suspended", func(t *testing.T) {
+		mockDB.Model(&admin).Update("suspended_at", time.Now())
+
+		rec := request("GET", "/users/me", impersonationToken, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const ADMIN_INVITATION_TTL = time.Hour * 72
+
+// CreateAdminInvitation godoc
+// @Summary Invite a new admin
+// @Description Allows an admin to create a single-use, expiring invitation token for registering another admin. The token is only returned once. Optionally restrict the invitation to a specific email address, or set how many hours it lasts (at most 720).
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param input body dtos.CreateInvitationRequest false "Invitation options"
+// @Success 201 {object} dtos.InvitationCreatedResponse "Invitation created successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid input data"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can invite admins"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/invitations [post]
+func CreateAdminInvitation(c *gin.Context) {
+	authUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+		return
+	}
+
+	user := authUser.(models.User)
+
+	if !user.HasPermission(models.PermissionAdminsInvite) {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Unauthorized access, only admins can invite admins"})
+		return
+	}
+
+	var req dtos.CreateInvitationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleValidationErrors(err, c)
+			return
+		}
+	}
+
+	ttl := ADMIN_INVITATION_TTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	invitation, token, err := NewAdminInvitation(&user.ID, req.Email, ttl)
+	if err != nil {
+		log.Printf("Failed to create admin invitation: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to create invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dtos.InvitationCreatedResponse{Invitation: *invitation, Token: token})
+}
+
+// ListAdminInvitations godoc
+// @Summary List pending admin invitations
+// @Description Allows an admin to list invitations that have not been used, revoked or expired.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.AdminInvitation "Successfully retrieved pending invitations"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can view invitations"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/invitations [get]
+func ListAdminInvitations(c *gin.Context) {
+	authUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+		return
+	}
+
+	user := authUser.(models.User)
+
+	if !user.HasPermission(models.PermissionAdminsInvite) {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Unauthorized access, only admins can view invitations"})
+		return
+	}
+
+	var invitations []models.AdminInvitation
+	result := db.DB.
+		Where("used_at IS NULL AND revoked_at IS NULL AND expires_at > ?", time.Now()).
+		Order("created_at DESC").
+		Find(&invitations)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeAdminInvitation godoc
+// @Summary Revoke an admin invitation
+// @Description Allows an admin to revoke a pending invitation so that it can no longer be used.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} models.AdminInvitation "Invitation revoked successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid invitation ID or invitation is no longer pending"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can revoke invitations"
+// @Failure 404 {object} dtos.ErrorResponse "Invitation not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/invitations/{id} [delete]
+func RevokeAdminInvitation(c *gin.Context) {
+	invitationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || invitationID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid invitation ID"})
+		return
+	}
+
+	authUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+		return
+	}
+
+	user := authUser.(models.User)
+
+	if !user.HasPermission(models.PermissionAdminsInvite) {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Unauthorized access, only admins can revoke invitations"})
+		return
+	}
+
+	var invitation models.AdminInvitation
+	result := db.DB.First(&invitation, invitationID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Invitation not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return
+	}
+
+	if !invitation.IsPending() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invitation is no longer pending"})
+		return
+	}
+
+	now := time.Now()
+	invitation.RevokedAt = &now
+	if err := db.DB.Save(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
+
+// NewAdminInvitation creates and stores an admin invitation, returning it along with
+// its plaintext token. createdByID is nil for invitations issued from the command line.
+func NewAdminInvitation(createdByID *uint, email string, ttl time.Duration) (*models.AdminInvitation, string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	invitation := models.AdminInvitation{
+		Email:       email,
+		TokenHash:   utils.HashToken(token),
+		CreatedByID: createdByID,
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+
+	if err := db.DB.Create(&invitation).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &invitation, token, nil
+}
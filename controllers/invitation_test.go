@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCreateAdminInvitation(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.AdminInvitation{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: "password"}
+	mockDB.Create(&admin)
+
+	t.Run("Successfully creates invitation", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/admin/invitations", func(c *gin.Context) {
+			c.Set("user", admin)
+			CreateAdminInvitation(c)
+		})
+
+		body, _ := json.Marshal(dtos.CreateInvitationRequest{Email: "new.admin@example.com", ExpiresInHours: 2})
+		req, _ := http.NewRequest("POST", "/admin/invitations", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var response dtos.InvitationCreatedResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.Token)
+		assert.Equal(t, "new.admin@example.com", response.Invitation.Email)
+		assert.Equal(t, admin.ID, *response.Invitation.CreatedByID)
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), response.Invitation.ExpiresAt, time.Minute)
+
+		// Only the hash of the token is stored
+		var stored models.AdminInvitation
+		mockDB.First(&stored, response.Invitation.ID)
+		assert.Equal(t, utils.HashToken(response.Token), stored.TokenHash)
+	})
+
+	t.Run("Fails when user is not an admin", func(t *testing.T) {
+		user := models.User{Email: "user@example.com", FirstName: "User", LastName: "Doe", Role: "customer", Password: "password"}
+		mockDB.Create(&user)
+
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/admin/invitations", func(c *gin.Context) {
+			c.Set("user", user)
+			CreateAdminInvitation(c)
+		})
+
+		req, _ := http.NewRequest("POST", "/admin/invitations", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Unauthorized access, only admins can invite admins", response.Error)
+	})
+
+	t.Run("Fails when the invitation would last longer than 30 days", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/admin/invitations", func(c *gin.Context) {
+			c.Set("user", admin)
+			CreateAdminInvitation(c)
+		})
+
+		body, _ := json.Marshal(dtos.CreateInvitationRequest{ExpiresInHours: 721})
+		req, _ := http.NewRequest("POST", "/admin/invitations", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var count int64
+		mockDB.Model(&models.AdminInvitation{}).Where("created_by_id = ? AND expires_at > ?", admin.ID, time.Now().Add(720*time.Hour)).Count(&count)
+		assert.Zero(t, count)
+	})
+}
+
+func TestListAndRevokeAdminInvitations(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.AdminInvitation{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: "password"}
+	mockDB.Create(&admin)
+
+	pending, _, _ := NewAdminInvitation(&admin.ID, "", ADMIN_INVITATION_TTL)
+	NewAdminInvitation(&admin.ID, "", -time.Hour)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", admin)
+	})
+	router.GET("/admin/invitations", ListAdminInvitations)
+	router.DELETE("/admin/invitations/:id", RevokeAdminInvitation)
+
+	t.Run("Lists only pending invitations", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/admin/invitations", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var invitations []models.AdminInvitation
+		err := json.Unmarshal(rec.Body.Bytes(), &invitations)
+		assert.NoError(t, err)
+		assert.Len(t, invitations, 1)
+		assert.Equal(t, pending.ID, invitations[0].ID)
+	})
+
+	t.Run("Successfully revokes invitation", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/admin/invitations/%d", pending.ID), nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var revoked models.AdminInvitation
+		mockDB.First(&revoked, pending.ID)
+		assert.NotNil(t, revoked.RevokedAt)
+		assert.False(t, revoked.IsPending())
+	})
+
+	t.Run("Fails when invitation is not pending", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/admin/invitations/%d", pending.ID), nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Fails when invitation does not exist", func(t *testing.T) {
+		req, _ := http.NewRequest("DELETE", "/admin/invitations/999", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
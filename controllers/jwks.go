@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/keyring"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS serves the public keys that verify our JWTs as a JSON Web Key Set, so other
+// services can check tokens without sharing a secret. It is mounted outside /v1 at
+// the conventional /.well-known/jwks.json path.
+func JWKS(c *gin.Context) {
+	k := keyring.Current()
+	if k == nil {
+		c.JSON(http.StatusServiceUnavailable, dtos.ErrorResponse{Error: "No signing keys are configured"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, k.JWKS())
+}
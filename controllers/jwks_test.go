@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/keyring"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWKS(t *testing.T) {
+	originalKeys := keyring.Current()
+	defer keyring.Set(originalKeys)
+
+	dir := t.TempDir()
+
+	oldKey, _ := keyring.GenerateKey("20240101T000000Z", "RS256")
+	keyring.WriteKey(dir, oldKey)
+
+	oldKeys, err := keyring.LoadDir(dir, "")
+	assert.NoError(t, err)
+	keyring.Set(oldKeys)
+
+	user := models.User{Email: "user@example.com", Role: "customer"}
+	user.ID = 1
+
+	oldTokens, err := models.GenerateJwtTokens(&user, "")
+	assert.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.GET("/.well-known/jwks.json", JWKS)
+
+	t.Run("Rotates to the newest key and keeps verifying older tokens", func(t *testing.T) {
+		newKey, _ := keyring.GenerateKey("20250101T000000Z", "EdDSA")
+		keyring.WriteKey(dir, newKey)
+
+		keys, err := keyring.LoadDir(dir, "")
+		assert.NoError(t, err)
+		assert.Equal(t, newKey.ID, keys.SigningKey().ID)
+		keyring.Set(keys)
+
+		newTokens, err := models.GenerateJwtTokens(&user, "")
+		assert.NoError(t, err)
+
+		token, _, _ := new(jwt.Parser).ParseUnverified(newTokens.AccessToken, jwt.MapClaims{})
+		assert.Equal(t, "EdDSA", token.Header["alg"])
+		assert.Equal(t, newKey.ID, token.Header["kid"])
+
+		_, err = models.ParseJwtToken(newTokens.AccessToken)
+		assert.NoError(t, err)
+
+		_, err = models.ParseJwtToken(oldTokens.AccessToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Publishes the public keys", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/.well-known/jwks.json", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var set keyring.JWKSet
+		json.Unmarshal(rec.Body.Bytes(), &set)
+		assert.Len(t, set.Keys, 2)
+		assert.Equal(t, "RSA", set.Keys[0].Kty)
+		assert.Equal(t, "RS256", set.Keys[0].Alg)
+		assert.NotEmpty(t, set.Keys[0].N)
+		assert.Equal(t, "OKP", set.Keys[1].Kty)
+		assert.Equal(t, "Ed25519", set.Keys[1].Crv)
+		assert.NotContains(t, rec.Body.String(), "\"d\"")
+	})
+
+	t.Run("Rejects tokens from unknown keys and algorithms", func(t *testing.T) {
+		strangerKey, _ := keyring.GenerateKey("20240101T000000Z", "EdDSA")
+		strangerKeys, _ := keyring.New(strangerKey)
+
+		forged, err := strangerKeys.Sign(jwt.MapClaims{"userID": 1, "type": models.TokenTypeAccess})
+		assert.NoError(t, err)
+		_, err = models.ParseJwtToken(forged)
+		assert.Error(t, err)
+
+		// A token signed with HS256 using a public key as the secret must not verify
+		hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"userID": 1, "type": models.TokenTypeAccess})
+		hmacToken.Header["kid"] = oldKey.ID
+		hmacString, _ := hmacToken.SignedString([]byte("!2E"))
+		_, err = models.ParseJwtToken(hmacString)
+		assert.Error(t, err)
+	})
+
+	t.Run("Refuses to load without a private key", func(t *testing.T) {
+		_, err := keyring.LoadDir(t.TempDir(), "")
+		assert.Error(t, err)
+
+		_, err = keyring.LoadDir(dir, "missing")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listMaxPageSize is the largest page a list endpoint returns. Larger page sizes are
+// lowered to it rather than rejected, as clients written before the limit send them.
+const listMaxPageSize = 100
+
+// listQuery describes the filters, sort keys and fields a list endpoint accepts. Query
+// parameters only reach SQL through the columns and conditions whitelisted here.
+type listQuery struct {
+	// filters maps query parameter names to the condition they apply.
+	filters map[string]listFilter
+	// sortColumns maps the keys accepted by the sort parameter to columns.
+	sortColumns map[string]string
+	// defaultSort is used when no sort parameter is given.
+	defaultSort string
+	// fields lists the JSON fields that can be requested with the fields parameter.
+	fields []string
+}
+
+// listFilter is a condition applied when its query parameter is present.
+type listFilter struct {
+	// condition is a WHERE clause with a placeholder for the parsed parameter, or none
+	// if parse is nil, in which case the parameter is a boolean that turns it on.
+	condition string
+	parse     func(string) (interface{}, error)
+}
+
+// listOptions holds the parsed pagination, sorting and field selection of a request.
+type listOptions struct {
+	page     int
+	pageSize int
+	order    string
+	fields   []string
+}
+
+func (o listOptions) offset() int {
+	return (o.page - 1) * o.pageSize
+}
+
+// parse applies the filters in the request to query and parses the remaining list
+// parameters, writing a 400 response if any of them is invalid.
+func (q listQuery) parse(c *gin.Context, query *gorm.DB) (*gorm.DB, listOptions, bool) {
+	var options listOptions
+	var err error
+
+	options.page, err = strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || options.page <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid page number"})
+		return nil, options, false
+	}
+
+	options.pageSize, err = strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || options.pageSize <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid pageSize number"})
+		return nil, options, false
+	}
+	options.pageSize = min(options.pageSize, listMaxPageSize)
+
+	for param, filter := range q.filters {
+		value, ok := c.GetQuery(param)
+		if !ok || value == "" {
+			continue
+		}
+
+		if filter.parse == nil {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Invalid %s, expected true or false", param)})
+				return nil, options, false
+			}
+			if enabled {
+				query = query.Where(filter.condition)
+			}
+			continue
+		}
+
+		parsed, err := filter.parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Invalid %s: %v", param, err)})
+			return nil, options, false
+		}
+		query = query.Where(filter.condition, parsed)
+	}
+
+	if options.order, err = q.order(c.DefaultQuery("sort", q.defaultSort)); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return nil, options, false
+	}
+
+	if options.fields, err = q.selectedFields(c.Query("fields")); err != nil {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return nil, options, false
+	}
+
+	return query, options, true
+}
+
+// order turns a comma-separated list of sort keys, each optionally prefixed with - for
+// descending order, into an ORDER BY clause. The id breaks ties so pages are stable.
+func (q listQuery) order(sort string) (string, error) {
+	var clauses []string
+	seen := map[string]bool{}
+
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			key, direction = key[1:], "DESC"
+		}
+
+		column, ok := q.sortColumns[key]
+		if !ok {
+			return "", fmt.Errorf("Invalid sort key: %s", key)
+		}
+		if seen[column] {
+			return "", fmt.Errorf("Duplicate sort key: %s", key)
+		}
+		seen[column] = true
+
+		clauses = append(clauses, column+" "+direction)
+	}
+
+	if !seen["id"] {
+		clauses = append(clauses, "id ASC")
+	}
+
+	return strings.Join(clauses, ", "), nil
+}
+
+// selectedFields parses a comma-separated list of fields. The id is always included.
+func (q listQuery) selectedFields(fields string) ([]string, error) {
+	if fields == "" {
+		return nil, nil
+	}
+
+	selected := []string{"id"}
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(q.fields, field) {
+			return nil, fmt.Errorf("Invalid field: %s", field)
+		}
+		if !slices.Contains(selected, field) {
+			selected = append(selected, field)
+		}
+	}
+
+	return selected, nil
+}
+
+// response builds the response for one page of items, listed under key, keeping only
+// the requested fields of each item.
+func (o listOptions) response(key string, totalCount int64, items interface{}) (gin.H, error) {
+	if o.fields != nil {
+		var err error
+		if items, err = sparseFieldset(items, o.fields); err != nil {
+			return nil, err
+		}
+	}
+
+	return gin.H{
+		"page":        o.page,
+		"page_size":   o.pageSize,
+		"total_count": totalCount,
+		"total_pages": int(math.Ceil(float64(totalCount) / float64(o.pageSize))),
+		key:           items,
+	}, nil
+}
+
+// sparseFieldset returns the JSON objects in items with only the given fields.
+func sparseFieldset(items interface{}, fields []string) ([]map[string]json.RawMessage, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		for field := range record {
+			if !slices.Contains(fields, field) {
+				delete(record, field)
+			}
+		}
+	}
+
+	return records, nil
+}
+
+func parseStringParam(value string) (interface{}, error) {
+	return value, nil
+}
+
+func parseFloatParam(value string) (interface{}, error) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return nil, errors.New("expected a number")
+	}
+	return number, nil
+}
+
+// parseTimeParam accepts an RFC 3339 timestamp or a date, which is read as midnight UTC.
+func parseTimeParam(value string) (interface{}, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return nil, errors.New("expected an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+// parseEnumParam returns a parser that only accepts the given values.
+func parseEnumParam(values ...string) func(string) (interface{}, error) {
+	return func(value string) (interface{}, error) {
+		if !slices.Contains(values, value) {
+			return nil, fmt.Errorf("expected one of %s", strings.Join(values, ", "))
+		}
+		return value, nil
+	}
+}
@@ -0,0 +1,259 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListAccountLockouts godoc
+// @Summary List active login lockouts
+// @Description Retrieve the email addresses and client IPs currently locked out after too many failed logins.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.AccountLockout "Successfully retrieved lockouts"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/lockouts [get]
+func ListAccountLockouts(c *gin.Context) {
+	var lockouts []models.AccountLockout
+	result := db.DB.
+		Where("unlocked_at IS NULL AND locked_until > ?", time.Now()).
+		Order("created_at DESC").
+		Find(&lockouts)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve lockouts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// UnlockAccountLockout godoc
+// @Summary Lift a login lockout
+// @Description Lift a lockout on an email address or client IP and reset its failed login count.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Lockout ID"
+// @Success 200 {object} models.AccountLockout "Lockout lifted successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid lockout ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "Lockout not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/lockouts/{id}/unlock [post]
+func UnlockAccountLockout(c *gin.Context) {
+	lockoutID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || lockoutID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid lockout ID"})
+		return
+	}
+
+	var lockout models.AccountLockout
+	result := db.DB.First(&lockout, lockoutID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Lockout not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return
+	}
+
+	admin := c.MustGet("user").(models.User)
+
+	if err := unlockLoginKey(lockout.Key, admin.ID); err != nil {
+		log.Printf("Failed to lift lockout %v: %v", lockout.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to lift lockout"})
+		return
+	}
+
+	db.DB.First(&lockout, lockout.ID)
+	c.JSON(http.StatusOK, lockout)
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user account
+// @Description Lift any login lockout on a user's email address and reset its failed login count.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} dtos.MessageResponse "Account unlocked successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "User not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/users/{id}/unlock [post]
+func UnlockUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	result := db.DB.First(&user, userID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return
+	}
+
+	admin := c.MustGet("user").(models.User)
+
+	if err := unlockLoginKey(emailThrottleKey(user.Email), admin.ID); err != nil {
+		log.Printf("Failed to unlock user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to unlock account"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "Account unlocked successfully"})
+}
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginThreshold returns how many consecutive failures lock out a throttle key. IPs
+// get a higher allowance since many customers may share one behind a NAT.
+func loginThreshold(key string) int {
+	if strings.HasPrefix(key, "ip:") {
+		return utils.GetEnvInt("LOGIN_IP_LOCKOUT_THRESHOLD", 20)
+	}
+	return utils.GetEnvInt("LOGIN_LOCKOUT_THRESHOLD", 5)
+}
+
+func loginLockoutDuration() time.Duration {
+	return utils.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+}
+
+// loginBackoff returns how long to wait after the given number of consecutive failures,
+// doubling with every failure up to the lockout duration.
+func loginBackoff(failures int) time.Duration {
+	base := utils.GetEnvDuration("LOGIN_BACKOFF_BASE", time.Second)
+	if base <= 0 || failures <= 0 {
+		return 0
+	}
+
+	backoff := float64(base) * math.Pow(2, float64(failures-1))
+	if limit := float64(loginLockoutDuration()); backoff > limit {
+		return time.Duration(limit)
+	}
+	return time.Duration(backoff)
+}
+
+// loginRetryAfter returns how long the client must wait before its next login attempt
+// is considered, or 0 if it may try now.
+func loginRetryAfter(keys ...string) (time.Duration, error) {
+	var throttles []models.LoginThrottle
+	if err := db.DB.Where(map[string]interface{}{"key": keys}).Find(&throttles).Error; err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, throttle := range throttles {
+		until := throttle.LastFailureAt.Add(loginBackoff(throttle.Failures))
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(until) {
+			until = *throttle.LockedUntil
+		}
+
+		if until.Sub(now) > wait {
+			wait = until.Sub(now)
+		}
+	}
+
+	return wait, nil
+}
+
+// recordLoginFailure counts a failed login against each key, locking out keys that
+// reach their threshold. Failures older than the lockout duration are forgotten.
+// The throttle row is locked while it is updated, so that concurrent failures are
+// all counted and cannot slip past the threshold.
+func recordLoginFailure(keys ...string) {
+	now := time.Now()
+
+	for _, key := range keys {
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).
+				Create(&models.LoginThrottle{Key: key, LastFailureAt: now}).Error
+			if err != nil {
+				return err
+			}
+
+			var throttle models.LoginThrottle
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(map[string]interface{}{"key": key}).First(&throttle).Error; err != nil {
+				return err
+			}
+
+			if now.Sub(throttle.LastFailureAt) > loginLockoutDuration() || (throttle.LockedUntil != nil && throttle.LockedUntil.Before(now)) {
+				throttle.Failures = 0
+				throttle.LockedUntil = nil
+			}
+
+			throttle.Failures++
+			throttle.LastFailureAt = now
+
+			if throttle.Failures >= loginThreshold(key) && throttle.LockedUntil == nil {
+				lockedUntil := now.Add(loginLockoutDuration())
+				throttle.LockedUntil = &lockedUntil
+
+				log.Printf("Locking out %v until %v after %d failed logins", key, lockedUntil, throttle.Failures)
+
+				lockout := models.AccountLockout{Key: key, Failures: throttle.Failures, LockedUntil: lockedUntil}
+				if err := tx.Create(&lockout).Error; err != nil {
+					return err
+				}
+			}
+
+			return tx.Save(&throttle).Error
+		})
+		if err != nil {
+			log.Printf("Failed to record failed login for %v: %v", key, err)
+		}
+	}
+}
+
+// resetLoginFailures forgets the failed logins counted against the keys.
+func resetLoginFailures(keys ...string) {
+	if err := db.DB.Where(map[string]interface{}{"key": keys}).Delete(&models.LoginThrottle{}).Error; err != nil {
+		log.Printf("Failed to reset failed logins for %v: %v", keys, err)
+	}
+}
+
+// unlockLoginKey lifts any active lockout on the key and resets its failure count.
+func unlockLoginKey(key string, unlockedByID uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(map[string]interface{}{"key": key}).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.AccountLockout{}).
+			Where(map[string]interface{}{"key": key}).
+			Where("unlocked_at IS NULL").
+			Updates(map[string]interface{}{"unlocked_at": time.Now(), "unlocked_by_id": unlockedByID}).Error
+	})
+}
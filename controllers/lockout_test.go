@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLoginLockout(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.LoginThrottle{}, &models.AccountLockout{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	user := models.User{Email: "user@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&user)
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: string(hashedPassword)}
+	mockDB.Create(&admin)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.POST("/login", LoginUser)
+
+	userAdmin := router.Group("/admin", func(c *gin.Context) {
+		c.Set("user", admin)
+	}, middleware.RequirePermission(models.PermissionUsersManage))
+	userAdmin.GET("/lockouts", ListAccountLockouts)
+	userAdmin.POST("/users/:id/unlock", UnlockUser)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(dtos.LoginRequest{Email: user.Email, Password: password})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Backs off after a failed login", func(t *testing.T) {
+		t.Setenv("LOGIN_BACKOFF_BASE", "1m")
+		defer resetLoginFailures(emailThrottleKey(user.Email), ipThrottleKey(""))
+
+		rec := login("wrongpassword")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = login("password")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("Locks out after repeated failures until an admin unlocks", func(t *testing.T) {
+		t.Setenv("LOGIN_BACKOFF_BASE", "0")
+		t.Setenv("LOGIN_LOCKOUT_THRESHOLD", "3")
+
+		for i := 0; i < 3; i++ {
+			rec := login("wrongpassword")
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+
+		rec := login("password")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Too many failed login attempts, please try again later", response.Error)
+
+		req, _ := http.NewRequest("GET", "/admin/lockouts", nil)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var lockouts []models.AccountLockout
+		json.Unmarshal(rec.Body.Bytes(), &lockouts)
+		assert.Len(t, lockouts, 1)
+		assert.Equal(t, emailThrottleKey(user.Email), lockouts[0].Key)
+
+		req, _ = http.NewRequest("POST", fmt.Sprintf("/admin/users/%d/unlock", user.ID), nil)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = login("password")
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var lockout models.AccountLockout
+		mockDB.First(&lockout, lockouts[0].ID)
+		assert.NotNil(t, lockout.UnlockedAt)
+		assert.Equal(t, admin.ID, *lockout.UnlockedByID)
+	})
+}
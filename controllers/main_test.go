@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"log"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/keyring"
+	"github.com/gin-gonic/gin"
+)
+
+// TestMain installs an ephemeral signing key so handlers can issue and verify tokens.
+func TestMain(m *testing.M) {
+	key, err := keyring.GenerateKey("test", "EdDSA")
+	if err != nil {
+		log.Fatalf("Failed to generate test signing key: %v", err)
+	}
+
+	keys, err := keyring.New(key)
+	if err != nil {
+		log.Fatalf("Failed to build test keyring: %v", err)
+	}
+	keyring.Set(keys)
+
+	os.Exit(m.Run())
+}
+
+// newTestContext returns a context for calling helpers that record the requesting client.
+func newTestContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	return c
+}
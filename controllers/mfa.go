@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/totp"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const MFA_RECOVERY_CODE_COUNT = 10
+
+// EnrollMFA godoc
+// @Summary Start MFA enrollment
+// @Description Generates a new TOTP secret and recovery codes for the logged in user. MFA is enabled once a code from the authenticator app is confirmed.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dtos.MFAEnrollmentResponse "Enrollment started"
+// @Failure 400 {object} dtos.ErrorResponse "MFA is already enabled"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/mfa/enroll [post]
+func EnrollMFA(c *gin.Context) {
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	if user.IsMFAEnabled() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "MFA is already enabled"})
+		return
+	}
+
+	enrollment, err := startMFAEnrollment(&user)
+	if err != nil {
+		log.Printf("Failed to start MFA enrollment for user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to start MFA enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmMFA godoc
+// @Summary Confirm MFA enrollment
+// @Description Enables MFA for the logged in user after verifying a code from their authenticator app.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body dtos.MFACodeRequest true "TOTP code"
+// @Success 200 {object} dtos.MessageResponse "MFA enabled successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid code or enrollment not started"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/mfa/confirm [post]
+func ConfirmMFA(c *gin.Context) {
+	var req dtos.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	if user.IsMFAEnabled() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "MFA is already enabled"})
+		return
+	}
+
+	if user.MFASecret == "" {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "MFA enrollment has not been started"})
+		return
+	}
+
+	if !useTOTPCode(&user, req.Code) {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid MFA code"})
+		return
+	}
+
+	if err := db.DB.Model(&user).Update("mfa_enabled_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to enable MFA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "MFA enabled successfully"})
+}
+
+// DisableMFA godoc
+// @Summary Disable MFA
+// @Description Disables MFA for the logged in user after verifying a current TOTP code. Admins, and users granted any permission through their roles, cannot disable MFA while it is mandatory for them.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body dtos.MFACodeRequest true "TOTP code"
+// @Success 200 {object} dtos.MessageResponse "MFA disabled successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid code or MFA not enabled"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "MFA is mandatory for admin accounts, or request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/mfa [delete]
+func DisableMFA(c *gin.Context) {
+	var req dtos.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.IsMFAEnabled() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "MFA is not enabled"})
+		return
+	}
+
+	required, err := mfaRequiredFor(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to disable MFA"})
+		return
+	}
+	if required {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "MFA is mandatory for admin accounts"})
+		return
+	}
+
+	if !useTOTPCode(&user, req.Code) {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid MFA code"})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"mfa_secret":         "",
+			"mfa_enabled_at":     nil,
+			"mfa_last_used_step": 0,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to disable MFA"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "MFA disabled successfully"})
+}
+
+// EnrollMFAForLogin godoc
+// @Summary Enroll in MFA during login
+// @Description Starts MFA enrollment for a user whose login was held back because MFA is mandatory for their account. Complete the login with a code through /login/mfa.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body dtos.MFAEnrollLoginRequest true "MFA challenge token"
+// @Success 200 {object} dtos.MFAEnrollmentResponse "Enrollment started"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error"
+// @Failure 401 {object} dtos.ErrorResponse "Invalid or expired MFA token"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /login/mfa/enroll [post]
+func EnrollMFAForLogin(c *gin.Context) {
+	var req dtos.MFAEnrollLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	user, enroll, err := parseMFAChallenge(req.MFAToken)
+	if err != nil || !enroll || user.IsMFAEnabled() {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Invalid or expired MFA token"})
+		return
+	}
+
+	enrollment, err := startMFAEnrollment(user)
+	if err != nil {
+		log.Printf("Failed to start MFA enrollment for user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to start MFA enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// VerifyMFALogin godoc
+// @Summary Complete a login with a second factor
+// @Description Exchanges the MFA challenge token returned by /login and a TOTP or recovery code for access and refresh tokens. Users enrolling during login must use a TOTP code, which also enables MFA.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body dtos.MFALoginRequest true "MFA challenge token and code"
+// @Success 200 {object} dtos.LoginSuccessResponse "Login successful"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error"
+// @Failure 401 {object} dtos.ErrorResponse "Invalid MFA code or token"
+// @Failure 429 {object} dtos.ErrorResponse "Too many failed attempts"
+// @Header 429 {integer} Retry-After "Seconds to wait before trying again"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /login/mfa [post]
+func VerifyMFALogin(c *gin.Context) {
+	var req dtos.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Either code or recovery_code is required"})
+		return
+	}
+
+	user, enroll, err := parseMFAChallenge(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Invalid or expired MFA token"})
+		return
+	}
+
+	throttleKey := fmt.Sprintf("mfa:%d", user.ID)
+
+	retryAfter, err := loginRetryAfter(throttleKey)
+	if err != nil {
+		log.Printf("Failed to check MFA throttle for %v: %v", user.Email, err)
+	}
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, dtos.ErrorResponse{Error: "Too many failed attempts, please try again later"})
+		return
+	}
+
+	var verified bool
+	switch {
+	case user.IsMFAEnabled() && req.Code != "":
+		verified = useTOTPCode(user, req.Code)
+	case user.IsMFAEnabled():
+		verified = useRecoveryCode(user, req.RecoveryCode)
+	case enroll && user.MFASecret != "" && req.Code != "":
+		verified = useTOTPCode(user, req.Code)
+		if verified {
+			now := time.Now()
+			user.MFAEnabledAt = &now
+			if err := db.DB.Model(user).Update("mfa_enabled_at", now).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to enable MFA"})
+				return
+			}
+		}
+	}
+
+	if !verified {
+		recordLoginFailure(throttleKey)
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Invalid MFA code"})
+		return
+	}
+
+	resetLoginFailures(throttleKey)
+	completeLogin(c, *user)
+}
+
+// startMFAEnrollment generates a new TOTP secret and set of recovery codes for the
+// user, replacing any enrollment that was started but not confirmed.
+func startMFAEnrollment(user *models.User) (*dtos.MFAEnrollmentResponse, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes := make([]string, MFA_RECOVERY_CODE_COUNT)
+	for i := range recoveryCodes {
+		if recoveryCodes[i], err = utils.GenerateRandomToken(6); err != nil {
+			return nil, err
+		}
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{"mfa_secret": secret, "mfa_last_used_step": 0}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		for _, code := range recoveryCodes {
+			if err := tx.Create(&models.MFARecoveryCode{UserID: user.ID, CodeHash: utils.HashToken(code)}).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.MFAEnrollmentResponse{
+		Secret:        secret,
+		OTPAuthURI:    totp.URI(utils.GetEnv("MFA_ISSUER", "E-Commerce API"), user.Email, secret),
+		RecoveryCodes: recoveryCodes,
+	}, nil
+}
+
+// useTOTPCode verifies a TOTP code for the user. Each code is accepted only once, so a
+// code observed by an attacker cannot be replayed within its validity window.
+func useTOTPCode(user *models.User, code string) bool {
+	step, ok := totp.Validate(user.MFASecret, code, time.Now())
+	if !ok || step <= user.MFALastUsedStep {
+		return false
+	}
+
+	result := db.DB.Model(&models.User{}).
+		Where("id = ? AND mfa_last_used_step < ?", user.ID, step).
+		Update("mfa_last_used_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	user.MFALastUsedStep = step
+	return true
+}
+
+// useRecoveryCode consumes one of the user's unused recovery codes.
+func useRecoveryCode(user *models.User, code string) bool {
+	result := db.DB.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(code)).
+		Update("used_at", time.Now())
+
+	return result.Error == nil && result.RowsAffected > 0
+}
+
+// parseMFAChallenge verifies an MFA challenge token and loads the user it was issued to.
+func parseMFAChallenge(tokenString string) (*models.User, bool, error) {
+	claims, err := models.ParseJwtToken(tokenString)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if tokenType, _ := claims["type"].(string); tokenType != models.TokenTypeMFAChallenge {
+		return nil, false, errors.New("token is not an MFA challenge token")
+	}
+
+	userID, _ := claims["userID"].(float64)
+	enroll, _ := claims["enroll"].(bool)
+
+	var user models.User
+	if err := db.DB.First(&user, uint(userID)).Error; err != nil {
+		return nil, false, err
+	}
+
+	if user.IsSuspended() {
+		return nil, false, errors.New("user is suspended")
+	}
+
+	return &user, enroll, nil
+}
+
+// mfaRequiredForAdmins reports whether admins must use MFA to log in.
+func mfaRequiredForAdmins() bool {
+	return utils.GetEnv("MFA_REQUIRED_FOR_ADMINS", "false") == "true"
+}
+
+// mfaRequiredFor reports whether user must use MFA to log in. Any user granted a
+// permission, by their base role or an assigned one, counts as an admin here.
+func mfaRequiredFor(user *models.User) (bool, error) {
+	if !mfaRequiredForAdmins() {
+		return false, nil
+	}
+
+	if err := user.LoadPermissions(db.DB); err != nil {
+		return false, err
+	}
+
+	return user.IsAdmin() || len(user.Permissions) > 0, nil
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/totp"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMFA(t *testing.T) {
+	t.Setenv("LOGIN_BACKOFF_BASE", "0")
+
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.LoginThrottle{}, &models.AccountLockout{}, &models.MFARecoveryCode{},
+		&models.Permission{}, &models.Role{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	user := models.User{Email: "user@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&user)
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: string(hashedPassword)}
+	mockDB.Create(&admin)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.POST("/login", LoginUser)
+	router.POST("/login/mfa", VerifyMFALogin)
+	router.POST("/login/mfa/enroll", EnrollMFAForLogin)
+
+	authenticated := router.Group("/users", func(c *gin.Context) {
+		var authUser models.User
+		mockDB.First(&authUser, user.ID)
+		c.Set("user", authUser)
+	})
+	authenticated.POST("/mfa/enroll", EnrollMFA)
+	authenticated.POST("/mfa/confirm", ConfirmMFA)
+	authenticated.DELETE("/mfa", DisableMFA)
+
+	send := func(method, path string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	login := func(email string) dtos.MFAChallengeResponse {
+		rec := send("POST", "/login", dtos.LoginRequest{Email: email, Password: "password"})
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+
+		var challenge dtos.MFAChallengeResponse
+		json.Unmarshal(rec.Body.Bytes(), &challenge)
+		assert.True(t, challenge.MFARequired)
+		assert.NotEmpty(t, challenge.MFAToken)
+		return challenge
+	}
+
+	var enrollment dtos.MFAEnrollmentResponse
+
+	t.Run("Enrolls and confirms MFA", func(t *testing.T) {
+		rec := send("POST", "/users/mfa/enroll", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		json.Unmarshal(rec.Body.Bytes(), &enrollment)
+		assert.NotEmpty(t, enrollment.Secret)
+		assert.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/")
+		assert.Len(t, enrollment.RecoveryCodes, MFA_RECOVERY_CODE_COUNT)
+
+		// Logins are unaffected until the enrollment is confirmed
+		rec = send("POST", "/login", dtos.LoginRequest{Email: user.Email, Password: "password"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = send("POST", "/users/mfa/confirm", dtos.MFACodeRequest{Code: "000000"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		code, _ := totp.GenerateCode(enrollment.Secret, time.Now())
+		rec = send("POST", "/users/mfa/confirm", dtos.MFACodeRequest{Code: code})
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Requires a second factor to log in", func(t *testing.T) {
+		challenge := login(user.Email)
+		assert.False(t, challenge.MFAEnrollmentRequired)
+
+		// The code used to confirm enrollment cannot be replayed
+		code, _ := totp.GenerateCode(enrollment.Secret, time.Now())
+		rec := send("POST", "/login/mfa", dtos.MFALoginRequest{MFAToken: challenge.MFAToken, Code: code})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		code, _ = totp.GenerateCode(enrollment.Secret, time.Now().Add(totp.Period))
+		rec = send("POST", "/login/mfa", dtos.MFALoginRequest{MFAToken: challenge.MFAToken, Code: code})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.LoginSuccessResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEmpty(t, response.RefreshToken)
+	})
+
+	t.Run("Accepts each recovery code once", func(t *testing.T) {
+		challenge := login(user.Email)
+
+		rec := send("POST", "/login/mfa", dtos.MFALoginRequest{MFAToken: challenge.MFAToken, RecoveryCode: enrollment.RecoveryCodes[0]})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = send("POST", "/login/mfa", dtos.MFALoginRequest{MFAToken: challenge.MFAToken, RecoveryCode: enrollment.RecoveryCodes[0]})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Rejects an access token as a challenge token", func(t *testing.T) {
+		tokens, _ := models.GenerateJwtTokens(&user, "")
+
+		rec := send("POST", "/login/mfa", dtos.MFALoginRequest{MFAToken: tokens.AccessToken, RecoveryCode: enrollment.RecoveryCodes[1]})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Requires admins to enroll when MFA is mandatory", func(t *testing.T) {
+		t.Setenv("MFA_REQUIRED_FOR_ADMINS", "true")
+
+		challenge := login(admin.Email)
+		assert.True(t, challenge.MFAEnrollmentRequired)
+
+		rec := send("POST", "/login/mfa/enroll", dtos.MFAEnrollLoginRequest{MFAToken: challenge.MFAToken})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var adminEnrollment dtos.MFAEnrollmentResponse
+		json.Unmarshal(rec.Body.Bytes(), &adminEnrollment)
+
+		code, _ := totp.GenerateCode(adminEnrollment.Secret, time.Now())
+		rec = send("POST", "/login/mfa", dtos.MFALoginRequest{MFAToken: challenge.MFAToken, Code: code})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var enrolled models.User
+		mockDB.First(&enrolled, admin.ID)
+		assert.True(t, enrolled.IsMFAEnabled())
+	})
+
+	t.Run("Requires users granted permissions to use MFA when it is mandatory", func(t *testing.T) {
+		t.Setenv("MFA_REQUIRED_FOR_ADMINS", "true")
+
+		permission := models.Permission{Name: models.PermissionProductsDelete}
+		mockDB.Create(&permission)
+		role := models.Role{Name: "catalog", Permissions: []models.Permission{permission}}
+		mockDB.Create(&role)
+
+		staff := models.User{Email: "staff@example.com", FirstName: "Staff", LastName: "User", Role: "customer", Password: string(hashedPassword)}
+		mockDB.Create(&staff)
+		mockDB.Model(&staff).Association("Roles").Append(&role)
+
+		challenge := login(staff.Email)
+		assert.True(t, challenge.MFAEnrollmentRequired)
+
+		mockDB.Model(&user).Association("Roles").Append(&role)
+		defer mockDB.Model(&user).Association("Roles").Clear()
+
+		code, _ := totp.GenerateCode(enrollment.Secret, time.Now())
+		rec := send("DELETE", "/users/mfa", dtos.MFACodeRequest{Code: code})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.JSONEq(t, `{"error":"MFA is mandatory for admin accounts"}`, rec.Body.String())
+	})
+
+	t.Run("Disables MFA", func(t *testing.T) {
+		code, _ := totp.GenerateCode(enrollment.Secret, time.Now().Add(2*totp.Period))
+		rec := send("DELETE", "/users/mfa", dtos.MFACodeRequest{Code: code})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		// The next period's code was used to log in above; rewind the replay guard
+		// rather than waiting for a fresh code
+		var current models.User
+		mockDB.First(&current, user.ID)
+		mockDB.Model(&current).Update("mfa_last_used_step", totp.Step(time.Now()))
+
+		code, _ = totp.GenerateCode(enrollment.Secret, time.Now().Add(totp.Period))
+		rec = send("DELETE", "/users/mfa", dtos.MFACodeRequest{Code: code})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var count int64
+		mockDB.Model(&models.MFARecoveryCode{}).Where("user_id = ?", user.ID).Count(&count)
+		assert.Zero(t, count)
+
+		rec = send("POST", "/login", dtos.LoginRequest{Email: user.Email, Password: "password"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/oidc"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OIDC_LOGIN_TTL is how long a user has to complete a login at an identity provider.
+const OIDC_LOGIN_TTL = 10 * time.Minute
+
+var (
+	errOIDCLoginUnavailable  = errors.New("login request is no longer available")
+	errOIDCEmailMissing      = errors.New("the identity provider did not share an email address")
+	errOIDCEmailUnverified   = errors.New("the identity provider has not verified this email address")
+	errOIDCLinkNotAllowed    = errors.New("accounts with admin privileges cannot be linked to an identity provider")
+	errOIDCAccountUnverified = errors.New("accounts with an unverified email cannot be linked to an identity provider")
+)
+
+// ListOIDCProviders godoc
+// @Summary List identity providers
+// @Description Returns the names of the OpenID Connect providers users can log in with.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dtos.OIDCProvidersResponse "Configured identity providers"
+// @Router /login/oidc [get]
+func ListOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, dtos.OIDCProvidersResponse{Providers: oidc.Names()})
+}
+
+// StartOIDCLogin godoc
+// @Summary Log in with an identity provider
+// @Description Redirects to the OpenID Connect provider's login page. After logging in there, the user is sent back to the provider's redirect URL with a code and state, which must be passed to the callback endpoint.
+// @Tags Auth
+// @Param provider path string true "Identity provider name"
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} dtos.ErrorResponse "Unknown identity provider"
+// @Failure 502 {object} dtos.ErrorResponse "The identity provider is unavailable"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /login/oidc/{provider} [get]
+func StartOIDCLogin(c *gin.Context) {
+	provider, ok := oidc.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Unknown identity provider"})
+		return
+	}
+
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to start login"})
+		return
+	}
+
+	nonce, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to start login"})
+		return
+	}
+
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to start login"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("Failed to reach identity provider %s: %v", provider.Name, err)
+		c.JSON(http.StatusBadGateway, dtos.ErrorResponse{Error: "The identity provider is unavailable"})
+		return
+	}
+
+	loginRequest := models.OIDCLoginRequest{
+		Provider:     provider.Name,
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OIDC_LOGIN_TTL),
+	}
+	if err := db.DB.Create(&loginRequest).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to start login"})
+		return
+	}
+
+	if err := db.DB.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginRequest{}).Error; err != nil {
+		log.Printf("Failed to purge expired OIDC login requests: %v", err)
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback godoc
+// @Summary Complete a login with an identity provider
+// @Description Redeems the code the identity provider returned and logs the user in. The identity is linked to the account with the same email address if both the provider and the account have verified it, and a new customer account is created if there is none. Accounts with admin privileges or an unverified email are never linked automatically. If MFA is enabled for the account, a 202 response with an MFA token is returned instead, as for password logins.
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "Identity provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} dtos.LoginSuccessResponse "Successfully logged in"
+// @Success 202 {object} dtos.MFAChallengeResponse "MFA code required"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid or expired login, or the identity provider reported an error"
+// @Failure 401 {object} dtos.ErrorResponse "The identity provider did not confirm the login"
+// @Failure 403 {object} dtos.ErrorResponse "Email not verified by the identity provider, or account suspended"
+// @Failure 404 {object} dtos.ErrorResponse "Unknown identity provider"
+// @Failure 409 {object} dtos.ErrorResponse "The matching account has admin privileges or an unverified email, and cannot be linked automatically"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /login/oidc/{provider}/callback [get]
+func OIDCCallback(c *gin.Context) {
+	provider, ok := oidc.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Unknown identity provider"})
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("The identity provider reported an error: %s", providerError)})
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid or expired login"})
+		return
+	}
+
+	loginRequest, err := consumeOIDCLoginRequest(provider.Name, state)
+	if err != nil {
+		if !errors.Is(err, errOIDCLoginUnavailable) {
+			log.Printf("Failed to look up OIDC login request: %v", err)
+		}
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid or expired login"})
+		return
+	}
+
+	idToken, err := provider.Exchange(c.Request.Context(), code, loginRequest.CodeVerifier, loginRequest.Nonce)
+	if err != nil {
+		log.Printf("Failed to complete login with identity provider %s: %v", provider.Name, err)
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "The identity provider did not confirm the login"})
+		return
+	}
+
+	user, err := userForExternalIdentity(provider.Name, idToken)
+	switch {
+	case errors.Is(err, errOIDCEmailMissing):
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "The identity provider did not share your email address"})
+		return
+	case errors.Is(err, errOIDCEmailUnverified):
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "The identity provider has not verified your email address"})
+		return
+	case errors.Is(err, errOIDCLinkNotAllowed):
+		c.JSON(http.StatusConflict, dtos.ErrorResponse{Error: "An account with this email already exists, log in with your password instead"})
+		return
+	case errors.Is(err, errOIDCAccountUnverified):
+		c.JSON(http.StatusConflict, dtos.ErrorResponse{Error: "An account with this email already exists, verify its email address before logging in with an identity provider"})
+		return
+	case err != nil:
+		log.Printf("Failed to link identity from %s: %v", provider.Name, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to log in"})
+		return
+	}
+
+	continueLogin(c, user)
+}
+
+// consumeOIDCLoginRequest marks the login request for state as used and returns it.
+func consumeOIDCLoginRequest(providerName, state string) (models.OIDCLoginRequest, error) {
+	var loginRequest models.OIDCLoginRequest
+
+	err := db.DB.Where("state_hash = ? AND provider = ?", utils.HashToken(state), providerName).
+		Limit(1).Find(&loginRequest).Error
+	if err != nil {
+		return loginRequest, err
+	}
+	if loginRequest.ID == 0 || loginRequest.UsedAt != nil || loginRequest.ExpiresAt.Before(time.Now()) {
+		return loginRequest, errOIDCLoginUnavailable
+	}
+
+	result := db.DB.Model(&models.OIDCLoginRequest{}).
+		Where("id = ? AND used_at IS NULL", loginRequest.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return loginRequest, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return loginRequest, errOIDCLoginUnavailable
+	}
+
+	return loginRequest, nil
+}
+
+// userForExternalIdentity returns the user linked to the identity, linking it to the
+// account with the same email address, if both the provider and the account have
+// verified it, or creating a new customer account if it is not linked yet.
+func userForExternalIdentity(providerName string, idToken *oidc.IDToken) (models.User, error) {
+	var user models.User
+	now := time.Now()
+
+	var identity models.ExternalIdentity
+	err := db.DB.Preload("User").
+		Where("provider = ? AND subject = ?", providerName, idToken.Subject).
+		Limit(1).Find(&identity).Error
+	if err != nil {
+		return user, err
+	}
+
+	if identity.ID != 0 {
+		err := db.DB.Model(&identity).Updates(map[string]interface{}{"email": idToken.Email, "last_login_at": now}).Error
+		if err != nil {
+			log.Printf("Failed to record login with identity %d: %v", identity.ID, err)
+		}
+		return identity.User, nil
+	}
+
+	if idToken.Email == "" {
+		return user, errOIDCEmailMissing
+	}
+	if !idToken.EmailVerified {
+		return user, errOIDCEmailUnverified
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("LOWER(email) = ?", strings.ToLower(idToken.Email)).Limit(1).Find(&user).Error; err != nil {
+			return err
+		}
+
+		if user.ID != 0 {
+			// A compromised identity provider must not be able to take over privileged accounts.
+			if err := user.LoadPermissions(tx); err != nil {
+				return err
+			}
+			if user.IsAdmin() || len(user.Permissions) > 0 {
+				return errOIDCLinkNotAllowed
+			}
+
+			// Whoever registered an unverified account may not own the email, and their
+			// password would keep working on the linked account
+			if !user.IsEmailVerified() {
+				return errOIDCAccountUnverified
+			}
+		} else {
+			firstName, lastName := idToken.GivenName, idToken.FamilyName
+			if firstName == "" && lastName == "" {
+				firstName, lastName, _ = strings.Cut(idToken.Name, " ")
+			}
+
+			user = models.User{
+				Email:           idToken.Email,
+				FirstName:       firstName,
+				LastName:        lastName,
+				Role:            models.RoleCustomer,
+				EmailVerifiedAt: &now,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.ExternalIdentity{
+			UserID:      user.ID,
+			Provider:    providerName,
+			Subject:     idToken.Subject,
+			Email:       idToken.Email,
+			LastLoginAt: &now,
+		}).Error
+	})
+
+	return user, err
+}
@@ -0,0 +1,318 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/oidc"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// stubOIDCProvider is a minimal OpenID Connect provider that issues ID tokens for
+// identities registered with authorize, checking the PKCE code verifier.
+type stubOIDCProvider struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]stubAuthorization
+}
+
+type stubAuthorization struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+func newStubOIDCProvider(t *testing.T) *stubOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub := &stubOIDCProvider{t: t, key: key, codes: make(map[string]stubAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 stub.URL,
+			"authorization_endpoint": stub.URL + "/authorize",
+			"token_endpoint":         stub.URL + "/token",
+			"jwks_uri":               stub.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub-key",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", stub.token)
+
+	stub.Server = httptest.NewServer(mux)
+	t.Cleanup(stub.Close)
+
+	return stub
+}
+
+// authorize plays the part of the user logging in at the provider: it accepts the
+// authorization URL the API redirected to and returns the code and state the
+// provider would send back.
+func (s *stubOIDCProvider) authorize(location string, claims jwt.MapClaims) (string, string) {
+	authURL, err := url.Parse(location)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	query := authURL.Query()
+
+	assert.Equal(s.t, s.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(s.t, "code", query.Get("response_type"))
+	assert.Equal(s.t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(s.t, "client", query.Get("client_id"))
+
+	code := query.Get("state") + "-code"
+
+	s.mu.Lock()
+	s.codes[code] = stubAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), claims: claims}
+	s.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func (s *stubOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(reason string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": reason})
+	}
+
+	if clientID, secret, _ := r.BasicAuth(); clientID != "client" || secret != "secret" {
+		fail("invalid_client")
+		return
+	}
+
+	s.mu.Lock()
+	authorization, ok := s.codes[r.PostFormValue("code")]
+	delete(s.codes, r.PostFormValue("code"))
+	s.mu.Unlock()
+
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" {
+		fail("invalid_grant")
+		return
+	}
+	if oidc.CodeChallenge(r.PostFormValue("code_verifier")) != authorization.challenge {
+		fail("invalid_grant")
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   s.URL,
+		"aud":   "client",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": authorization.nonce,
+	}
+	for name, value := range authorization.claims {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "stub-key"
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "opaque", "token_type": "Bearer", "id_token": idToken})
+}
+
+func TestOIDCLogin(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.RefreshToken{}, &models.Session{},
+		&models.ExternalIdentity{}, &models.OIDCLoginRequest{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	stub := newStubOIDCProvider(t)
+	oidc.Set(oidc.NewProvider(oidc.Config{
+		Name:         "stub",
+		IssuerURL:    stub.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3000/login/callback",
+	}))
+	defer oidc.Set()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+
+	verifiedAt := time.Now()
+	bob := models.User{Email: "bob@example.com", FirstName: "Bob", LastName: "Jones", Role: "customer", Password: string(hashedPassword), EmailVerifiedAt: &verifiedAt}
+	mockDB.Create(&bob)
+
+	dave := models.User{Email: "dave@example.com", FirstName: "Dave", LastName: "Brown", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&dave)
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: string(hashedPassword)}
+	mockDB.Create(&admin)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.GET("/login/oidc", ListOIDCProviders)
+	router.GET("/login/oidc/:provider", StartOIDCLogin)
+	router.GET("/login/oidc/:provider/callback", OIDCCallback)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	callback := func(code, state string) *httptest.ResponseRecorder {
+		return get("/login/oidc/stub/callback?" + url.Values{"code": {code}, "state": {state}}.Encode())
+	}
+
+	// login goes through the whole flow for the identity described by claims.
+	login := func(claims jwt.MapClaims) *httptest.ResponseRecorder {
+		rec := get("/login/oidc/stub")
+		assert.Equal(t, http.StatusFound, rec.Code)
+
+		return callback(stub.authorize(rec.Header().Get("Location"), claims))
+	}
+
+	loggedInUser := func(rec *httptest.ResponseRecorder) models.User {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.LoginSuccessResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEmpty(t, response.RefreshToken)
+		return response.User
+	}
+
+	countUsers := func() int64 {
+		var count int64
+		mockDB.Model(&models.User{}).Count(&count)
+		return count
+	}
+
+	t.Run("Lists configured providers", func(t *testing.T) {
+		rec := get("/login/oidc")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"providers":["stub"]}`, rec.Body.String())
+
+		rec = get("/login/oidc/unknown")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Creates a customer account for a new identity", func(t *testing.T) {
+		user := loggedInUser(login(jwt.MapClaims{
+			"sub": "alice-sub", "email": "alice@example.com", "email_verified": true, "given_name": "Alice", "family_name": "Smith",
+		}))
+
+		assert.Equal(t, "alice@example.com", user.Email)
+		assert.Equal(t, "Alice", user.FirstName)
+		assert.Equal(t, models.RoleCustomer, user.Role)
+		assert.True(t, user.IsEmailVerified())
+
+		var identity models.ExternalIdentity
+		mockDB.Where("provider = ? AND subject = ?", "stub", "alice-sub").First(&identity)
+		assert.Equal(t, user.ID, identity.UserID)
+
+		// The same identity logs in to the same account, even after changing email
+		before := countUsers()
+		again := loggedInUser(login(jwt.MapClaims{"sub": "alice-sub", "email": "alice@new.example.com", "email_verified": true}))
+		assert.Equal(t, user.ID, again.ID)
+		assert.Equal(t, before, countUsers())
+	})
+
+	t.Run("Links an existing account by verified email", func(t *testing.T) {
+		user := loggedInUser(login(jwt.MapClaims{"sub": "bob-sub", "email": "BOB@example.com", "email_verified": "true"}))
+		assert.Equal(t, bob.ID, user.ID)
+
+		var identity models.ExternalIdentity
+		mockDB.Where("subject = ?", "bob-sub").First(&identity)
+		assert.Equal(t, bob.ID, identity.UserID)
+
+		// The password keeps working
+		var updated models.User
+		mockDB.First(&updated, bob.ID)
+		assert.NoError(t, updated.IsValidPassword("password"))
+	})
+
+	t.Run("Rejects identities without a verified email", func(t *testing.T) {
+		before := countUsers()
+
+		rec := login(jwt.MapClaims{"sub": "mallory-sub", "email": "bob@example.com", "email_verified": false})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = login(jwt.MapClaims{"sub": "carol-sub", "email_verified": true})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		assert.Equal(t, before, countUsers())
+	})
+
+	t.Run("Does not link admin accounts", func(t *testing.T) {
+		rec := login(jwt.MapClaims{"sub": "admin-sub", "email": admin.Email, "email_verified": true})
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		var count int64
+		mockDB.Model(&models.ExternalIdentity{}).Where("user_id = ?", admin.ID).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Does not link accounts with an unverified email", func(t *testing.T) {
+		rec := login(jwt.MapClaims{"sub": "dave-sub", "email": dave.Email, "email_verified": true})
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		var count int64
+		mockDB.Model(&models.ExternalIdentity{}).Where("user_id = ?", dave.ID).Count(&count)
+		assert.Zero(t, count)
+
+		var unchanged models.User
+		mockDB.First(&unchanged, dave.ID)
+		assert.False(t, unchanged.IsEmailVerified())
+	})
+
+	t.Run("Rejects reused state and mismatched nonce", func(t *testing.T) {
+		rec := get("/login/oidc/stub")
+		code, state := stub.authorize(rec.Header().Get("Location"), jwt.MapClaims{"sub": "alice-sub"})
+
+		loggedInUser(callback(code, state))
+
+		rec = callback(code, state)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = callback("bogus", "bogus")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = login(jwt.MapClaims{"sub": "alice-sub", "nonce": "forged"})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
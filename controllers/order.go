@@ -1,53 +1,88 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"math"
 	"net/http"
 	"strconv"
 
 	"github.com/cgzirim/ecommerce-api/db"
 	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
 	"github.com/cgzirim/ecommerce-api/models"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	// errInsufficientStock marks orders for more of a product or variant than is in stock.
+	errInsufficientStock = errors.New("insufficient stock")
+	// errOrderStatusChanged marks status changes that lost a race with another one.
+	errOrderStatusChanged = errors.New("order status changed")
 )
 
 // CreateOrder godoc
 // @Summary Create a new order
-// @Description Allows a user to create a new order with the specified address and items.
+// @Description Allows a user to create a new order with the specified address and items. The user's default shipping address is used if no address is specified, and the billing address defaults to the default billing address, then to the shipping address. The order keeps a copy of both addresses, so later changes to the address book do not affect it. Items of products with variants must name the variant, whose price applies. Ordering takes the items from the stock of their variant, or of their product if it has no variants.
 // @Tags Order
 // @Accept json
 // @Produce json
 // @Param input body dtos.CreateOrderRequest true "Order information"
 // @Success 201 {object} models.Order "Order created successfully"
-// @Failure 400 {object} dtos.ErrorResponse "Invalid input data"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid input data, a product with variants ordered without a variant, insufficient stock, or no address given and no default shipping address set"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Email address must be verified before placing orders, or request made with an API key"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /orders [post]
 func CreateOrder(c *gin.Context) {
+	user, ok := requireUserSession(c)
+	if !ok {
+		return
+	}
+
 	var createOrderRequest dtos.CreateOrderRequest
 	if err := c.ShouldBindJSON(&createOrderRequest); err != nil {
 		handleValidationErrors(err, c)
 		return
 	}
 
-	authUser, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+	if requireVerifiedEmailForOrders() && !user.IsEmailVerified() {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Email address must be verified before placing orders"})
 		return
 	}
 
-	user, ok := authUser.(models.User)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+	shippingAddress, err := findOrderAddress(user.ID, createOrderRequest.AddressID, "is_default_shipping")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve address"})
+		return
+	}
+	if shippingAddress.ID == 0 {
+		if createOrderRequest.AddressID != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid address ID: %d", createOrderRequest.AddressID)})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "address_id is required when no default shipping address is set"})
+		}
 		return
 	}
 
-	// loop through the order items and validate the product ID and quantity, and
+	billingAddress, err := findOrderAddress(user.ID, createOrderRequest.BillingAddressID, "is_default_billing")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve address"})
+		return
+	}
+	if billingAddress.ID == 0 {
+		if createOrderRequest.BillingAddressID != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid billing address ID: %d", createOrderRequest.BillingAddressID)})
+			return
+		}
+		billingAddress = shippingAddress
+	}
+
+	// loop through the order items and validate the product, variant and quantity, and
 	// calculate the total order amount
 	var orderTotal float64
-	products := make(map[uint]models.Product)
+	orderItems := make([]models.OrderItem, 0, len(createOrderRequest.OrderItems))
 	for _, item := range createOrderRequest.OrderItems {
 		var product models.Product
 		if err := db.DB.First(&product, item.ProductID).Error; err != nil {
@@ -64,46 +99,79 @@ func CreateOrder(c *gin.Context) {
 			return
 		}
 
-		products[product.ID] = product
-		orderTotal += product.Price * float64(item.Quantity)
-	}
+		orderItem := models.OrderItem{ProductID: product.ID, Quantity: item.Quantity}
+		price := product.Price
 
-	order := models.Order{
-		UserID:    user.ID,
-		AddressID: createOrderRequest.AddressID,
-		Total:     float64(orderTotal),
-		Status:    models.OrderStatusPending,
-	}
+		// Products with variants can only be ordered by variant
+		var variant models.ProductVariant
+		variantQuery := db.DB.Where("product_id = ?", product.ID)
+		if item.VariantID != 0 {
+			variantQuery = variantQuery.Where("id = ?", item.VariantID)
+		}
+		if err := variantQuery.Limit(1).Find(&variant).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve product variant"})
+			return
+		}
+		if item.VariantID != 0 && variant.ID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid variant ID %d for product ID: %d", item.VariantID, item.ProductID),
+			})
+			return
+		}
+		if item.VariantID == 0 && variant.ID != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("variant_id is required for product ID: %d", item.ProductID),
+			})
+			return
+		}
+		if variant.ID != 0 {
+			orderItem.VariantID = &variant.ID
+			orderItem.SKU = variant.SKU
+			price = variant.Price
+		}
 
-	if err := db.DB.Create(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		orderItem.Price = price * float64(item.Quantity)
+		orderItems = append(orderItems, orderItem)
+		orderTotal += orderItem.Price
 	}
 
-	// loop through the order items and create the order items
-	for _, item := range createOrderRequest.OrderItems {
-		product := products[item.ProductID]
+	order := models.Order{
+		UserID:          user.ID,
+		AddressID:       shippingAddress.ID,
+		ShippingAddress: models.NewOrderAddress(shippingAddress),
+		BillingAddress:  models.NewOrderAddress(billingAddress),
+		Total:           float64(orderTotal),
+		Status:          models.OrderStatusPending,
+		StockReserved:   true,
+	}
 
-		orderItem := models.OrderItem{
-			OrderID:   order.ID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     product.Price * float64(item.Quantity),
+	// Take the stock and create the order and its items together, so that a failure
+	// leaves neither a partial order nor stock taken for nothing
+	var outOfStock *models.OrderItem
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := reserveStock(tx, orderItems, &outOfStock); err != nil {
+			return err
 		}
 
-		if err := db.DB.Create(&orderItem).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		if err := tx.Create(&order).Error; err != nil {
+			return err
 		}
 
+		for i := range orderItems {
+			orderItems[i].OrderID = order.ID
+		}
+		return tx.Create(&orderItems).Error
+	})
+	if errors.Is(err, errInsufficientStock) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": insufficientStockMessage(outOfStock)})
+		return
 	}
-
-	if err := db.DB.Save(&order).Error; err != nil {
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := db.DB.Preload("User").Preload("Address").Preload("OrderItems").First(&order, order.ID).Error; err != nil {
+	if err := db.DB.Preload("User").Preload("OrderItems").First(&order, order.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -111,21 +179,66 @@ func CreateOrder(c *gin.Context) {
 	c.JSON(http.StatusCreated, order)
 }
 
+// findOrderAddress returns the user's address with the given ID, or their default
+// address of the kind named by defaultColumn if id is 0. The address has ID 0 if
+// there is none.
+func findOrderAddress(userID, id uint, defaultColumn string) (models.Address, error) {
+	var address models.Address
+
+	query := db.DB.Where("user_id = ?", userID)
+	if id != 0 {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where(defaultColumn+" = ?", true)
+	}
+
+	err := query.Limit(1).Find(&address).Error
+	return address, err
+}
+
+// orderListQuery whitelists the filters, sort keys and fields of ListOrders.
+var orderListQuery = listQuery{
+	filters: map[string]listFilter{
+		"status":        {condition: "status = ?", parse: parseEnumParam(models.OrderStatusPending, models.OrderStatusCompleted, models.OrderStatusCancelled)},
+		"min_total":     {condition: "total >= ?", parse: parseFloatParam},
+		"max_total":     {condition: "total <= ?", parse: parseFloatParam},
+		"created_after": {condition: "created_at > ?", parse: parseTimeParam},
+	},
+	sortColumns: map[string]string{
+		"id":         "id",
+		"status":     "status",
+		"total":      "total",
+		"created_at": "created_at",
+	},
+	defaultSort: "-created_at",
+	fields: []string{
+		"id", "user", "address_id", "shipping_address", "billing_address", "total", "status", "order_items",
+		"created_at", "updated_at",
+	},
+}
+
 // ListOrders godoc
 // @Summary List orders for a specific user
-// @Description Retrieve a paginated list of orders for a specific user. Non-admin users can only list their own orders. Admins can list orders for any user.
+// @Description Retrieve a paginated list of orders for a specific user, optionally filtered and sorted. Non-admin users can only list their own orders. Admins can list orders for any user. Use fields to only return some fields of each order; the id is always returned.
 // @Tags Order
 // @Accept json
 // @Produce json
 // @Param user_id path int true "User ID"
 // @Param page query int false "Page number" default(1)
-// @Param pageSize query int false "Number of orders per page" default(10)
+// @Param pageSize query int false "Number of orders per page, lowered to 100 if larger" default(10)
+// @Param status query string false "Filter by status" Enums(pending, completed, cancelled)
+// @Param min_total query number false "Minimum order total"
+// @Param max_total query number false "Maximum order total"
+// @Param created_after query string false "Only orders placed after this RFC 3339 timestamp or YYYY-MM-DD date"
+// @Param sort query string false "Comma-separated sort keys, each prefixed with - for descending order, e.g. status,-total. Keys: id, status, total, created_at" default(-created_at)
+// @Param fields query string false "Comma-separated fields to return, e.g. status,total. Fields: id, user, address_id, shipping_address, billing_address, total, status, order_items, created_at, updated_at"
 // @Success 200 {object} dtos.OrderListResponse "Successfully retrieved the paginated list of orders"
-// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID or page parameters"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID or query parameter"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
-// @Failure 403 {object} dtos.ErrorResponse "Unauthorized, you can only view your own orders"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized, you can only view your own orders, or an API key without the orders:read_all scope"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /orders/{user_id} [get]
 func ListOrders(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("user_id"))
@@ -134,55 +247,57 @@ func ListOrders(c *gin.Context) {
 		return
 	}
 
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil || page <= 0 {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid page number"})
+	authUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
 		return
 	}
 
-	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
-	if err != nil || pageSize <= 0 {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid pageSize number"})
+	user := authUser.(models.User)
+
+	// Keys only reach orders through the permission, not as their owner
+	if !user.HasPermission(models.PermissionOrdersReadAll) && middleware.IsAPIKeyRequest(c) {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "API keys need the orders:read_all scope to view orders"})
 		return
 	}
 
-	offset := (page - 1) * pageSize
-
-	authUser, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+	if !user.HasPermission(models.PermissionOrdersReadAll) && user.ID != uint(userID) {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Unauthorized, you can only view your own orders"})
 		return
 	}
 
-	user := authUser.(models.User)
+	query, options, ok := orderListQuery.parse(c, db.DB.Model(&models.Order{}).Where("user_id = ?", userID))
+	if !ok {
+		return
+	}
 
-	if !user.IsAdmin() && user.ID != uint(userID) {
-		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Unauthorized, you can only view your own orders"})
+	var totalOrders int64
+	if err := query.Count(&totalOrders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve orders"})
 		return
 	}
 
-	var orders []models.Order
-	result := db.DB.Preload("User").Preload("Address").Preload("OrderItems").Where("user_id = ?", userID).Limit(pageSize).Offset(offset).Find(&orders)
+	orders := []models.Order{}
+	result := query.Preload("User").Preload("OrderItems").
+		Order(options.order).Limit(options.pageSize).Offset(options.offset()).
+		Find(&orders)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
 		return
 	}
 
-	var totalOrders int64
-	db.DB.Model(&models.Order{}).Where("user_id = ?", userID).Count(&totalOrders)
-
-	c.JSON(http.StatusOK, dtos.OrderListResponse{
-		Page:       page,
-		PageSize:   pageSize,
-		TotalCount: totalOrders,
-		TotalPages: int(math.Ceil(float64(totalOrders) / float64(pageSize))),
-		Orders:     orders,
-	})
+	response, err := options.response("orders", totalOrders, orders)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve orders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // CancelOrder godoc
 // @Summary Cancel an order
-// @Description Allows the owner of an order to cancel it if it is still in the pending status.
+// @Description Allows the owner of an order to cancel it if it is still in the pending status. The stock taken by its items is returned.
 // @Tags Order
 // @Accept json
 // @Produce json
@@ -190,8 +305,9 @@ func ListOrders(c *gin.Context) {
 // @Success 200 {object} models.Order "Order cancelled successfully"
 // @Failure 400 {object} dtos.ErrorResponse "Invalid order ID or order cannot be cancelled"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
-// @Failure 403 {object} dtos.ErrorResponse "Unauthorized, you can only cancel your own orders"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized, you can only cancel your own orders, or request made with an API key"
 // @Failure 404 {object} dtos.ErrorResponse "Order not found"
+// @Failure 409 {object} dtos.ErrorResponse "Order changed by a concurrent request"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /orders/{id}/cancel [patch]
@@ -202,14 +318,11 @@ func CancelOrder(c *gin.Context) {
 		return
 	}
 
-	authUser, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+	user, ok := requireUserSession(c)
+	if !ok {
 		return
 	}
 
-	user := authUser.(models.User)
-
 	var order models.Order
 	result := db.DB.First(&order, orderID)
 	if result.Error != nil {
@@ -231,9 +344,7 @@ func CancelOrder(c *gin.Context) {
 		return
 	}
 
-	order.Status = models.OrderStatusCancelled
-	if err := db.DB.Save(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+	if !changeOrderStatus(c, &order, models.OrderStatusCancelled) {
 		return
 	}
 
@@ -242,19 +353,21 @@ func CancelOrder(c *gin.Context) {
 
 // UpdateOrderStatus godoc
 // @Summary Update the status of an order
-// @Description Allows an admin to update the status of an order.
+// @Description Allows an admin to update the status of an order. Cancelling an order returns the stock taken by its items, and reopening a cancelled order takes it again. Orders placed before stock was tracked leave stock unchanged.
 // @Tags Order
 // @Accept json
 // @Produce json
 // @Param id path int true "Order ID"
 // @Param input body dtos.UpdateOrderStatusRequest true "Order status update information"
 // @Success 200 {object} models.Order "Order status updated successfully"
-// @Failure 400 {object} dtos.ErrorResponse "Invalid order ID or status"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid order ID or status, or insufficient stock to reopen the order"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
 // @Failure 403 {object} dtos.ErrorResponse "Unauthorized, only admins can update order status"
 // @Failure 404 {object} dtos.ErrorResponse "Order not found"
+// @Failure 409 {object} dtos.ErrorResponse "Order changed by a concurrent request"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /orders/{id}/status [patch]
 func UpdateOrderStatus(c *gin.Context) {
 	orderID, err := strconv.Atoi(c.Param("id"))
@@ -271,7 +384,7 @@ func UpdateOrderStatus(c *gin.Context) {
 
 	user := authUser.(models.User)
 
-	if !user.IsAdmin() {
+	if !user.HasPermission(models.PermissionOrdersUpdateStatus) {
 		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Unauthorized, only admins can update order status"})
 		return
 	}
@@ -298,11 +411,115 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	order.Status = req.Status
-	if err := db.DB.Save(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+	if !changeOrderStatus(c, &order, req.Status) {
 		return
 	}
 
 	c.JSON(http.StatusOK, order)
 }
+
+// changeOrderStatus moves order to status, writing an error response if it fails.
+// Cancelling an order returns its stock, and reopening a cancelled order takes it again,
+// for orders that reserved stock when placed.
+// The change only applies if the order still has the status it was loaded with, so
+// concurrent changes cannot return or take its stock twice.
+func changeOrderStatus(c *gin.Context, order *models.Order, status string) bool {
+	var outOfStock *models.OrderItem
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", order.ID, order.Status).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOrderStatusChanged
+		}
+
+		if !order.StockReserved {
+			return nil
+		}
+
+		cancelled := models.OrderStatusCancelled
+		if status == cancelled && order.Status != cancelled {
+			return releaseStock(tx, order.ID)
+		}
+		if status != cancelled && order.Status == cancelled {
+			var items []models.OrderItem
+			if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+				return err
+			}
+			return reserveStock(tx, items, &outOfStock)
+		}
+		return nil
+	})
+	if errors.Is(err, errInsufficientStock) {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: insufficientStockMessage(outOfStock)})
+		return false
+	}
+	if errors.Is(err, errOrderStatusChanged) {
+		c.JSON(http.StatusConflict, dtos.ErrorResponse{Error: "Order was changed by another request, please try again"})
+		return false
+	}
+	if err == nil {
+		err = db.DB.First(order, order.ID).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+		return false
+	}
+
+	return true
+}
+
+// reserveStock takes the stock of the ordered items. The decrement only applies while
+// enough stock is left, so concurrent orders cannot oversell an item. If an item is out
+// of stock, it is stored in outOfStock and errInsufficientStock is returned.
+func reserveStock(tx *gorm.DB, items []models.OrderItem, outOfStock **models.OrderItem) error {
+	for i, item := range items {
+		result := stockOf(tx, item).
+			Where("stock >= ?", item.Quantity).
+			Update("stock", gorm.Expr("stock - ?", item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			*outOfStock = &items[i]
+			return errInsufficientStock
+		}
+	}
+
+	return nil
+}
+
+// releaseStock returns the stock taken by the items of an order that is cancelled.
+func releaseStock(tx *gorm.DB, orderID uint) error {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := stockOf(tx, item).Update("stock", gorm.Expr("stock + ?", item.Quantity)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stockOf scopes tx to the row holding the stock of an order item: its variant if it
+// has one, otherwise its product.
+func stockOf(tx *gorm.DB, item models.OrderItem) *gorm.DB {
+	if item.VariantID != nil {
+		return tx.Model(&models.ProductVariant{}).Where("id = ?", *item.VariantID)
+	}
+	return tx.Model(&models.Product{}).Where("id = ?", item.ProductID)
+}
+
+func insufficientStockMessage(item *models.OrderItem) string {
+	if item.VariantID != nil {
+		return fmt.Sprintf("Insufficient stock for variant %s of product ID: %d", item.SKU, item.ProductID)
+	}
+	return fmt.Sprintf("Insufficient stock for product ID: %d", item.ProductID)
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -19,7 +20,7 @@ import (
 
 func TestCreateOrder(t *testing.T) {
 	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	mockDB.AutoMigrate(&models.Order{}, &models.User{}, &models.Address{}, &models.Product{}, &models.OrderItem{})
+	mockDB.AutoMigrate(&models.Order{}, &models.User{}, &models.Address{}, &models.Product{}, &models.ProductImage{}, &models.OrderItem{})
 
 	// Override the global DB variable with the mock DB and reset it after the test
 	originalDB := db.DB
@@ -32,7 +33,7 @@ func TestCreateOrder(t *testing.T) {
 	address := models.Address{FirstName: "John", LastName: "Doe", City: "CityA", Country: "CountryA", ZipCode: "12345", StreetAddress: "Street 1", UserID: user.ID}
 	mockDB.Create(&address)
 
-	product := models.Product{Name: "Product A", Price: 10.0}
+	product := models.Product{Name: "Product A", Price: 10.0, Stock: 100}
 	mockDB.Create(&product)
 
 	t.Run("Successfully creates order", func(t *testing.T) {
@@ -63,10 +64,12 @@ func TestCreateOrder(t *testing.T) {
 		assert.NoError(t, err)
 
 		var reloadedOrder models.Order
-		mockDB.Preload("User").Preload("Address").Preload("OrderItems").First(&reloadedOrder, createdOrder.ID)
+		mockDB.Preload("User").Preload("OrderItems").First(&reloadedOrder, createdOrder.ID)
 
 		assert.Equal(t, user.ID, reloadedOrder.UserID)
 		assert.Equal(t, address.ID, reloadedOrder.AddressID)
+		assert.Equal(t, models.NewOrderAddress(address), reloadedOrder.ShippingAddress)
+		assert.Equal(t, models.NewOrderAddress(address), reloadedOrder.BillingAddress)
 		assert.Equal(t, models.OrderStatusPending, reloadedOrder.Status)
 		assert.Equal(t, 20.0, reloadedOrder.Total)
 		assert.Len(t, reloadedOrder.OrderItems, 1)
@@ -103,6 +106,37 @@ func TestCreateOrder(t *testing.T) {
 		assert.Equal(t, "Quantity must be greater than 0 for product ID: 1", response["error"])
 	})
 
+	t.Run("Fails when email verification is required", func(t *testing.T) {
+		t.Setenv("REQUIRE_VERIFIED_EMAIL_FOR_ORDERS", "true")
+
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/orders", func(c *gin.Context) {
+			c.Set("user", user)
+			CreateOrder(c)
+		})
+
+		orderRequest := dtos.CreateOrderRequest{
+			AddressID: address.ID,
+			OrderItems: []dtos.OrderItemRequest{
+				{ProductID: product.ID, Quantity: 1},
+			},
+		}
+		body, _ := json.Marshal(orderRequest)
+		req, _ := http.NewRequest("POST", "/orders", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Email address must be verified before placing orders", response.Error)
+	})
+
 	t.Run("Fails when user is unauthenticated", func(t *testing.T) {
 		gin.SetMode(gin.TestMode)
 
@@ -128,11 +162,207 @@ func TestCreateOrder(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, "Unauthenticated, login is required", response.Error)
 	})
+
+	t.Run("Uses the default shipping address when none is given", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/orders", func(c *gin.Context) {
+			c.Set("user", user)
+			CreateOrder(c)
+		})
+
+		placeOrder := func(addressID uint) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(dtos.CreateOrderRequest{
+				AddressID:  addressID,
+				OrderItems: []dtos.OrderItemRequest{{ProductID: product.ID, Quantity: 1}},
+			})
+			req, _ := http.NewRequest("POST", "/orders", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := placeOrder(0)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		defaultAddress := models.Address{FirstName: "John", LastName: "Doe", City: "CityB", Country: "CountryB", ZipCode: "67890", StreetAddress: "Street 2", UserID: user.ID, IsDefaultShipping: true}
+		mockDB.Create(&defaultAddress)
+
+		rec = placeOrder(0)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var createdOrder models.Order
+		json.Unmarshal(rec.Body.Bytes(), &createdOrder)
+		assert.Equal(t, defaultAddress.ID, createdOrder.AddressID)
+		assert.Equal(t, "Street 2", createdOrder.ShippingAddress.StreetAddress)
+
+		// Addresses of other users cannot be used
+		other := models.User{Email: "other@example.com", FirstName: "Jane", LastName: "Roe", Role: "customer", Password: "password"}
+		mockDB.Create(&other)
+		othersAddress := models.Address{FirstName: "Jane", LastName: "Roe", City: "CityC", Country: "CountryC", ZipCode: "11111", StreetAddress: "Street 3", UserID: other.ID}
+		mockDB.Create(&othersAddress)
+
+		rec = placeOrder(othersAddress.ID)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Keeps the addresses the order was placed with", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/orders", func(c *gin.Context) {
+			c.Set("user", user)
+			CreateOrder(c)
+		})
+
+		shipping := models.Address{FirstName: "John", LastName: "Doe", City: "CityD", Country: "CountryD", ZipCode: "22222", StreetAddress: "Street 4", UserID: user.ID}
+		billing := models.Address{FirstName: "John", LastName: "Doe", City: "CityE", Country: "CountryE", ZipCode: "33333", StreetAddress: "Street 5", UserID: user.ID}
+		mockDB.Create(&shipping)
+		mockDB.Create(&billing)
+
+		body, _ := json.Marshal(dtos.CreateOrderRequest{
+			AddressID:        shipping.ID,
+			BillingAddressID: billing.ID,
+			OrderItems:       []dtos.OrderItemRequest{{ProductID: product.ID, Quantity: 1}},
+		})
+		req, _ := http.NewRequest("POST", "/orders", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var createdOrder models.Order
+		json.Unmarshal(rec.Body.Bytes(), &createdOrder)
+
+		mockDB.Model(&shipping).Update("street_address", "Street 6")
+		mockDB.Delete(&billing)
+
+		var reloadedOrder models.Order
+		mockDB.First(&reloadedOrder, createdOrder.ID)
+		assert.Equal(t, "Street 4", reloadedOrder.ShippingAddress.StreetAddress)
+		assert.Equal(t, "Street 5", reloadedOrder.BillingAddress.StreetAddress)
+		assert.Equal(t, "CityE", reloadedOrder.BillingAddress.City)
+	})
+
+	t.Run("Takes the ordered items from stock", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/orders", func(c *gin.Context) {
+			c.Set("user", user)
+			CreateOrder(c)
+		})
+
+		placeOrder := func(items ...dtos.OrderItemRequest) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(dtos.CreateOrderRequest{AddressID: address.ID, OrderItems: items})
+			req, _ := http.NewRequest("POST", "/orders", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			return rec
+		}
+
+		scarce := models.Product{Name: "Product B", Price: 5.0, Stock: 3}
+		mockDB.Create(&scarce)
+
+		rec := placeOrder(dtos.OrderItemRequest{ProductID: scarce.ID, Quantity: 2})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		mockDB.First(&scarce, scarce.ID)
+		assert.Equal(t, 1, scarce.Stock)
+		var placed models.Order
+		mockDB.Last(&placed)
+		assert.True(t, placed.StockReserved)
+
+		var stockBefore models.Product
+		mockDB.First(&stockBefore, product.ID)
+		var ordersBefore int64
+		mockDB.Model(&models.Order{}).Count(&ordersBefore)
+
+		// Nothing is taken or created when any item is short
+		rec = placeOrder(
+			dtos.OrderItemRequest{ProductID: product.ID, Quantity: 1},
+			dtos.OrderItemRequest{ProductID: scarce.ID, Quantity: 2},
+		)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, fmt.Sprintf(`{"error":"Insufficient stock for product ID: %d"}`, scarce.ID), rec.Body.String())
+
+		var stockAfter models.Product
+		mockDB.First(&stockAfter, product.ID)
+		assert.Equal(t, stockBefore.Stock, stockAfter.Stock)
+		var ordersAfter int64
+		mockDB.Model(&models.Order{}).Count(&ordersAfter)
+		assert.Equal(t, ordersBefore, ordersAfter)
+	})
+}
+
+func TestListOrders(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.Order{}, &models.User{}, &models.Address{}, &models.Product{}, &models.ProductImage{}, &models.OrderItem{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Email: "test@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: "password"}
+	mockDB.Create(&user)
+	other := models.User{Email: "other@example.com", FirstName: "Jane", LastName: "Roe", Role: "customer", Password: "password"}
+	mockDB.Create(&other)
+
+	for _, order := range []models.Order{
+		{UserID: user.ID, Total: 30, Status: models.OrderStatusCompleted},
+		{UserID: user.ID, Total: 10, Status: models.OrderStatusPending},
+		{UserID: user.ID, Total: 20, Status: models.OrderStatusPending},
+		{UserID: other.ID, Total: 40, Status: models.OrderStatusPending},
+	} {
+		mockDB.Create(&order)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/orders/:user_id", func(c *gin.Context) {
+		c.Set("user", user)
+		ListOrders(c)
+	})
+
+	list := func(query string) (int, []map[string]interface{}) {
+		req, _ := http.NewRequest("GET", "/orders/"+strconv.Itoa(int(user.ID))+"?"+query, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var response struct {
+			Orders []map[string]interface{} `json:"orders"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response.Orders
+	}
+
+	t.Run("Filters and sorts the user's orders", func(t *testing.T) {
+		code, orders := list("status=pending&sort=-total")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, orders, 2)
+		assert.Equal(t, float64(20), orders[0]["total"])
+		assert.Equal(t, float64(10), orders[1]["total"])
+
+		_, orders = list("min_total=15&sort=status,total&fields=status,total")
+		assert.Equal(t, []map[string]interface{}{
+			{"id": float64(1), "status": "completed", "total": float64(30)},
+			{"id": float64(3), "status": "pending", "total": float64(20)},
+		}, orders)
+	})
+
+	t.Run("Rejects parameters outside the whitelist", func(t *testing.T) {
+		for _, query := range []string{"status=shipped", "sort=user_id", "fields=password"} {
+			code, _ := list(query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+		}
+	})
 }
 
 func TestCancelOrder(t *testing.T) {
 	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	mockDB.AutoMigrate(&models.Order{}, &models.User{}, &models.Address{}, &models.Product{}, &models.OrderItem{})
+	mockDB.AutoMigrate(&models.Order{}, &models.User{}, &models.Address{}, &models.Product{}, &models.ProductImage{}, &models.OrderItem{})
 
 	originalDB := db.DB
 	db.SetMockDB(mockDB)
@@ -215,11 +445,48 @@ func TestCancelOrder(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, "Order cannot be cancelled, it is not in pending state", response.Error)
 	})
+
+	t.Run("Cancelling returns the stock of the items", func(t *testing.T) {
+		product := models.Product{Name: "Product A", Price: 10.0, Stock: 3}
+		mockDB.Create(&product)
+
+		pending := models.Order{UserID: user.ID, AddressID: address.ID, Total: 20.0, Status: models.OrderStatusPending, StockReserved: true}
+		mockDB.Create(&pending)
+		mockDB.Create(&models.OrderItem{OrderID: pending.ID, ProductID: product.ID, Price: 20.0, Quantity: 2})
+		legacy := models.Order{UserID: user.ID, AddressID: address.ID, Total: 20.0, Status: models.OrderStatusPending}
+		mockDB.Create(&legacy)
+		mockDB.Create(&models.OrderItem{OrderID: legacy.ID, ProductID: product.ID, Price: 20.0, Quantity: 2})
+
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.PATCH("/orders/:id/cancel", func(c *gin.Context) {
+			c.Set("user", user)
+			CancelOrder(c)
+		})
+
+		req, _ := http.NewRequest("PATCH", "/orders/"+strconv.Itoa(int(pending.ID))+"/cancel", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		mockDB.First(&product, product.ID)
+		assert.Equal(t, 5, product.Stock)
+
+		// Orders placed before stock was tracked never took any
+		req, _ = http.NewRequest("PATCH", "/orders/"+strconv.Itoa(int(legacy.ID))+"/cancel", nil)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		mockDB.First(&product, product.ID)
+		assert.Equal(t, 5, product.Stock)
+	})
 }
 
 func TestUpdateOrderStatus(t *testing.T) {
 	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	mockDB.AutoMigrate(&models.Order{}, &models.User{}, &models.Address{}, &models.Product{}, &models.OrderItem{})
+	mockDB.AutoMigrate(&models.Order{}, &models.User{}, &models.Address{}, &models.Product{}, &models.ProductImage{}, &models.OrderItem{})
 
 	originalDB := db.DB
 	db.SetMockDB(mockDB)
@@ -344,4 +611,45 @@ func TestUpdateOrderStatus(t *testing.T) {
 		assert.Equal(t, "Unauthorized, only admins can update order status", response.Error)
 	})
 
+	t.Run("Reopening a cancelled order takes its stock again", func(t *testing.T) {
+		product := models.Product{Name: "Product A", Price: 10.0, Stock: 1}
+		mockDB.Create(&product)
+
+		cancelled := models.Order{UserID: user.ID, AddressID: address.ID, Total: 20.0, Status: models.OrderStatusCancelled, StockReserved: true}
+		mockDB.Create(&cancelled)
+		mockDB.Create(&models.OrderItem{OrderID: cancelled.ID, ProductID: product.ID, Price: 20.0, Quantity: 2})
+
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.PATCH("/orders/:id/status", func(c *gin.Context) {
+			c.Set("user", admin)
+			UpdateOrderStatus(c)
+		})
+
+		setStatus := func(status string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(dtos.UpdateOrderStatusRequest{Status: status})
+			req, _ := http.NewRequest("PATCH", "/orders/"+strconv.Itoa(int(cancelled.ID))+"/status", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := setStatus(models.OrderStatusPending)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		mockDB.First(&cancelled, cancelled.ID)
+		assert.Equal(t, models.OrderStatusCancelled, cancelled.Status)
+
+		mockDB.Model(&product).Update("stock", 2)
+		rec = setStatus(models.OrderStatusPending)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockDB.First(&product, product.ID)
+		assert.Equal(t, 0, product.Stock)
+
+		rec = setStatus(models.OrderStatusCancelled)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockDB.First(&product, product.ID)
+		assert.Equal(t, 2, product.Stock)
+	})
 }
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/mailer"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const PASSWORD_RESET_TTL = time.Hour
+
+var errResetTokenUnavailable = errors.New("password reset token is no longer available")
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Sends a single-use password reset token to the given email address if an account exists for it. The response is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body dtos.ForgotPasswordRequest true "Account email"
+// @Success 202 {object} dtos.MessageResponse "Password reset requested"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error"
+// @Router /password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var req dtos.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	response := dtos.MessageResponse{Msg: "If an account exists for this email, a password reset link has been sent"}
+
+	var users []models.User
+	if err := db.DB.Where("email = ?", req.Email).Limit(1).Find(&users).Error; err != nil || len(users) == 0 {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	user := users[0]
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		log.Printf("Failed to generate password reset token: %v", err)
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	resetToken := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(PASSWORD_RESET_TTL),
+	}
+
+	if err := db.DB.Create(&resetToken).Error; err != nil {
+		log.Printf("Failed to store password reset token for user %v: %v", user.Email, err)
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	err = mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Use the link below to choose a new password. It expires in %v.\n\n%s?token=%s",
+			PASSWORD_RESET_TTL, utils.GetEnv("PASSWORD_RESET_URL", "http://localhost:8080/reset-password"), token,
+		),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to %v: %v", user.Email, err)
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// ResetPassword godoc
+// @Summary Reset a forgotten password
+// @Description Sets a new password using a token from the password reset email. All existing sessions of the user are logged out.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body dtos.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} dtos.MessageResponse "Password reset successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error, mismatched passwords, password rejected by the password policy or invalid token"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var req dtos.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	if req.Password != req.PasswordConfirm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passwords do not match"})
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	err := db.DB.Where("token_hash = ?", utils.HashToken(req.Token)).First(&resetToken).Error
+	if err != nil || resetToken.UsedAt != nil || resetToken.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, resetToken.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+		return
+	}
+
+	if !checkPasswordPolicy(c, req.Password, user.Email) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Failed to hash password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenUnavailable
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", resetToken.UserID).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+
+		// A session stolen before the reset must not survive it
+		return revokeUserSessions(tx, resetToken.UserID)
+	})
+	if err != nil {
+		if errors.Is(err, errResetTokenUnavailable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired password reset token"})
+			return
+		}
+
+		log.Printf("Failed to reset password for user %v: %v", resetToken.UserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "Password reset successfully"})
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/mailer"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/passwordpolicy"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// writeBreachedList writes the passwords as a sorted list of SHA-1 hashes with counts.
+func writeBreachedList(t *testing.T, passwords ...string) string {
+	lines := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeBreachedRanges writes the passwords as a directory of range files, one per
+// hash prefix, each listing the hash suffixes with counts.
+func writeBreachedRanges(t *testing.T, passwords ...string) string {
+	dir := t.TempDir()
+	ranges := map[string][]string{}
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		ranges[hash[:5]] = append(ranges[hash[:5]], fmt.Sprintf("%s:%d", hash[5:], i+1))
+	}
+
+	for prefix, lines := range ranges {
+		if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(strings.Join(lines, "\r\n")), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBreachedList(t *testing.T) {
+	t.Run("Reads a directory of range files", func(t *testing.T) {
+		dir := writeBreachedRanges(t, "password1", "letmein123")
+
+		// The range API pads responses with made-up suffixes counted 0
+		sum := sha1.Sum([]byte("correct-horse"))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(hash[5:]+":0\r\n"), 0o600)
+
+		list, err := passwordpolicy.OpenBreachedList(dir)
+		assert.NoError(t, err)
+		defer list.Close()
+
+		for password, breached := range map[string]bool{"password1": true, "letmein123": true, "correct-horse": false, "Tr0ub4dor&3": false} {
+			found, err := list.Contains(password)
+			assert.NoError(t, err)
+			assert.Equal(t, breached, found, password)
+		}
+	})
+
+	t.Run("Reads a full dump", func(t *testing.T) {
+		list, err := passwordpolicy.OpenBreachedList(writeBreachedList(t, "password1", "letmein123"))
+		assert.NoError(t, err)
+		defer list.Close()
+
+		found, err := list.Contains("letmein123")
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		found, err = list.Contains("Tr0ub4dor&3")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("Rejects files that are not lists of hashes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "suffixes.txt")
+		os.WriteFile(path, []byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"), 0o600)
+
+		_, err := passwordpolicy.OpenBreachedList(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	t.Setenv("LOGIN_BACKOFF_BASE", "0")
+
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.Session{},
+		&models.EmailVerificationToken{}, &models.LoginThrottle{}, &models.AccountLockout{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	mailer.SetSender(&recordingSender{})
+	defer mailer.SetSender(mailer.LogSender{})
+
+	breached := []string{"password1", "letmein123"}
+	for i := 0; i < 200; i++ {
+		breached = append(breached, fmt.Sprintf("filler-%d", i))
+	}
+	breached = append(breached, "correct-horse")
+
+	list, err := passwordpolicy.OpenBreachedList(writeBreachedList(t, breached...))
+	assert.NoError(t, err)
+	defer list.Close()
+
+	policy := passwordpolicy.Default()
+	policy.MinCharacterClasses = 2
+	policy.Breached = list
+	passwordpolicy.Set(policy)
+	defer passwordpolicy.Set(passwordpolicy.Default())
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(middleware.LoadAuthUserMiddleware())
+	router.POST("/register", RegisterCustomer)
+	router.POST("/users/me/password", ChangePassword)
+
+	request := func(path, accessToken string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	register := func(email, password string) *httptest.ResponseRecorder {
+		return request("/register", "", dtos.CustomerRegistrationRequest{
+			Email: email, FirstName: "John", LastName: "Doe", Password: password, PasswordConfirm: password,
+		})
+	}
+
+	rejection := func(rec *httptest.ResponseRecorder) string {
+		var response struct {
+			Error map[string]string `json:"error"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return response.Error["password"]
+	}
+
+	t.Run("Rejects passwords that break the policy at registration", func(t *testing.T) {
+		cases := map[string]string{
+			"Short1":                 "at least 8 characters",
+			strings.Repeat("a1", 37): "at most 72 bytes",
+			"onlylowercase":          "at least 2 of",
+			"Johnsmith.shop1":        "based on your email",
+			"letmein123":             "data breach",
+			"password1":              "data breach",
+		}
+		for password, message := range cases {
+			rec := register("john.smith@example.com", password)
+			assert.Equal(t, http.StatusBadRequest, rec.Code, password)
+			assert.Contains(t, rejection(rec), message, password)
+		}
+
+		var count int64
+		mockDB.Model(&models.User{}).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Accepts a password that satisfies the policy", func(t *testing.T) {
+		rec := register("john.smith@example.com", "Tr0ub4dor&3")
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+
+	t.Run("Applies the policy when changing a password", func(t *testing.T) {
+		var user models.User
+		mockDB.Where("email = ?", "john.smith@example.com").First(&user)
+		tokens, _ := issueTokens(newTestContext(), &user, "")
+
+		rec := request("/users/me/password", tokens.AccessToken, dtos.ChangePasswordRequest{
+			CurrentPassword: "Tr0ub4dor&3", Password: "correct-horse", PasswordConfirm: "correct-horse",
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rejection(rec), "data breach")
+
+		for _, password := range breached {
+			found, err := list.Contains(password)
+			assert.NoError(t, err)
+			assert.True(t, found, password)
+		}
+		found, err := list.Contains("Tr0ub4dor&3")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+}
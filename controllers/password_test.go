@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/mailer"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// recordingSender captures outgoing email instead of delivering it.
+type recordingSender struct {
+	messages []mailer.Message
+}
+
+func (s *recordingSender) Send(msg mailer.Message) error {
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// tokenFromMessage extracts the token query parameter from a link in an email body.
+func tokenFromMessage(msg mailer.Message) string {
+	return msg.Body[strings.LastIndex(msg.Body, "token=")+len("token="):]
+}
+
+func TestPasswordReset(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.PasswordResetToken{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	sender := &recordingSender{}
+	mailer.SetSender(sender)
+	defer mailer.SetSender(mailer.LogSender{})
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	user := models.User{Email: "user@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&user)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.POST("/password/forgot", ForgotPassword)
+	router.POST("/password/reset", ResetPassword)
+
+	request := func(path string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Does not reveal whether an account exists", func(t *testing.T) {
+		rec := request("/password/forgot", dtos.ForgotPasswordRequest{Email: "nobody@example.com"})
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Empty(t, sender.messages)
+	})
+
+	t.Run("Successfully resets password", func(t *testing.T) {
+		session, _ := issueTokens(newTestContext(), &user, "")
+
+		rec := request("/password/forgot", dtos.ForgotPasswordRequest{Email: user.Email})
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Len(t, sender.messages, 1)
+		assert.Equal(t, user.Email, sender.messages[0].To)
+
+		token := tokenFromMessage(sender.messages[0])
+
+		rec = request("/password/reset", dtos.ResetPasswordRequest{Token: token, Password: "newpassword", PasswordConfirm: "newpassword"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var updated models.User
+		mockDB.First(&updated, user.ID)
+		assert.NoError(t, updated.IsValidPassword("newpassword"))
+		assert.NotNil(t, updated.TokensValidAfter)
+
+		var refresh models.RefreshToken
+		mockDB.Where("jti = ?", session.Refresh.JTI).First(&refresh)
+		assert.NotNil(t, refresh.RevokedAt)
+
+		// Reset tokens are single-use
+		rec = request("/password/reset", dtos.ResetPasswordRequest{Token: token, Password: "another", PasswordConfirm: "another"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Keeps the password when sessions cannot be revoked", func(t *testing.T) {
+		sender.messages = nil
+		rec := request("/password/forgot", dtos.ForgotPasswordRequest{Email: user.Email})
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		token := tokenFromMessage(sender.messages[0])
+
+		mockDB.Migrator().DropTable(&models.Session{})
+		defer mockDB.AutoMigrate(&models.Session{})
+
+		rec = request("/password/reset", dtos.ResetPasswordRequest{Token: token, Password: "thirdpassword", PasswordConfirm: "thirdpassword"})
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		var updated models.User
+		mockDB.First(&updated, user.ID)
+		assert.NoError(t, updated.IsValidPassword("newpassword"))
+
+		var resetToken models.PasswordResetToken
+		mockDB.Where("token_hash = ?", utils.HashToken(token)).First(&resetToken)
+		assert.Nil(t, resetToken.UsedAt)
+	})
+
+	t.Run("Fails with an invalid token", func(t *testing.T) {
+		rec := request("/password/reset", dtos.ResetPasswordRequest{Token: "bogus", Password: "newpassword", PasswordConfirm: "newpassword"})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Invalid or expired password reset token", response.Error)
+	})
+}
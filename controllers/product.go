@@ -3,7 +3,6 @@ package controllers
 import (
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"strconv"
 
@@ -12,59 +11,112 @@ import (
 	"github.com/cgzirim/ecommerce-api/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// inStockCondition matches products with stock to sell: their own stock if they have no
+// variants, otherwise that of any of their variants.
+const inStockCondition = "((NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id) AND products.stock > 0)" +
+	" OR EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.stock > 0))"
+
+// productMinPrice, productMaxPrice and productStock are the price range and stock of
+// products across their variants, or their own for products without variants.
+const (
+	productMinPrice = "COALESCE((SELECT MIN(product_variants.price) FROM product_variants WHERE product_variants.product_id = products.id), products.price)"
+	productMaxPrice = "COALESCE((SELECT MAX(product_variants.price) FROM product_variants WHERE product_variants.product_id = products.id), products.price)"
+	productStock    = "COALESCE((SELECT SUM(product_variants.stock) FROM product_variants WHERE product_variants.product_id = products.id), products.stock)"
+)
+
+// productListQuery whitelists the filters, sort keys and fields of ListProducts.
+var productListQuery = listQuery{
+	filters: map[string]listFilter{
+		"category":      {condition: "category_id IN ?", parse: parseCategoryParam},
+		"min_price":     {condition: productMaxPrice + " >= ?", parse: parseFloatParam},
+		"max_price":     {condition: productMinPrice + " <= ?", parse: parseFloatParam},
+		"in_stock":      {condition: inStockCondition},
+		"created_after": {condition: "created_at > ?", parse: parseTimeParam},
+	},
+	sortColumns: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"price":      productMinPrice,
+		"stock":      productStock,
+		"created_at": "created_at",
+	},
+	defaultSort: "id",
+	fields:      []string{"id", "name", "category_id", "category", "description", "price", "stock", "images", "created_at", "updated_at"},
+}
+
 // ListProducts godoc
 // @Summary Retrieve a paginated list of products
-// @Description Retrieve a paginated list of products with the ability to specify page and page size.
+// @Description Retrieve a paginated list of products, optionally filtered and sorted. Use fields to only return some fields of each product; the id is always returned.
 // @Tags Product
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number" default(1)
-// @Param pageSize query int false "Number of products per page" default(10)
+// @Param pageSize query int false "Number of products per page, lowered to 100 if larger" default(10)
+// @Param category query string false "Filter by category slug, including its subcategories"
+// @Param min_price query number false "Minimum price, which a product with variants meets if any variant does"
+// @Param max_price query number false "Maximum price, which a product with variants meets if any variant does"
+// @Param in_stock query bool false "Only products in stock, or with a variant in stock"
+// @Param created_after query string false "Only products created after this RFC 3339 timestamp or YYYY-MM-DD date"
+// @Param sort query string false "Comma-separated sort keys, each prefixed with - for descending order, e.g. -price,name. Keys: id, name, price, stock, created_at. Products with variants sort by their cheapest variant and the total stock of their variants" default(id)
+// @Param fields query string false "Comma-separated fields to return, e.g. name,price. Fields: id, name, category_id, category, description, price, stock, images, created_at, updated_at"
 // @Success 200 {object} dtos.ProductListResponse "Successfully retrieved the paginated list of products"
-// @Failure 400 {object} dtos.ErrorResponse "Invalid page number or pageSize"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid query parameter"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Router /products [get]
 func ListProducts(c *gin.Context) {
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil || page <= 0 {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid page number"})
+	response, ok := listProducts(c, db.DB.Model(&models.Product{}))
+	if !ok {
 		return
 	}
 
-	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
-	if err != nil || pageSize <= 0 {
-		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid pageSize number"})
-		return
-	}
+	c.JSON(http.StatusOK, response)
+}
 
-	offset := (page - 1) * pageSize
+// listProducts builds the response for one page of the products matching base and the
+// list parameters of the request, writing an error response if that fails.
+func listProducts(c *gin.Context, base *gorm.DB) (gin.H, bool) {
+	query, options, ok := productListQuery.parse(c, base)
+	if !ok {
+		return nil, false
+	}
 
-	var products []models.Product
+	var totalProducts int64
+	if err := query.Count(&totalProducts).Error; err != nil {
+		log.Printf("Failed to count products: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve products"})
+		return nil, false
+	}
 
-	result := db.DB.Limit(pageSize).Offset(offset).Find(&products)
+	products := []models.Product{}
+	result := preloadProductImages(query.Preload("Category")).
+		Order(options.order).Limit(options.pageSize).Offset(options.offset()).
+		Find(&products)
 	if result.Error != nil {
 		log.Printf("Failed to retrieve products: %v", result.Error)
-		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
-		return
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve products"})
+		return nil, false
+	}
+	for i := range products {
+		setProductGalleryURLs(&products[i])
 	}
 
-	var totalProducts int64
-	db.DB.Model(&models.Product{}).Count(&totalProducts)
-
-	c.JSON(http.StatusOK, gin.H{
-		"page":        page,
-		"page_size":   pageSize,
-		"total_count": totalProducts,
-		"total_pages": int(math.Ceil(float64(totalProducts) / float64(pageSize))),
-		"products":    products,
-	})
+	response, err := options.response("products", totalProducts, products)
+	if err != nil {
+		log.Printf("Failed to select product fields: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve products"})
+		return nil, false
+	}
+
+	return response, true
 }
 
 // GetProductByID godoc
 // @Summary Retrieve a product by ID
-// @Description Retrieve a product by its unique ID.
+// @Description Retrieve a product by its unique ID, with its options and variants.
 // @Tags Product
 // @Accept json
 // @Produce json
@@ -82,7 +134,7 @@ func GetProductByID(c *gin.Context) {
 	}
 
 	var product models.Product
-	result := db.DB.First(&product, productID)
+	result := preloadProductImages(preloadProductVariants(db.DB.Preload("Category"))).First(&product, productID)
 	if result.Error != nil {
 		if result.Error.Error() == "record not found" {
 			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Product not found"})
@@ -93,6 +145,7 @@ func GetProductByID(c *gin.Context) {
 		return
 	}
 
+	setProductGalleryURLs(&product)
 	c.JSON(http.StatusOK, product)
 }
 
@@ -104,11 +157,12 @@ func GetProductByID(c *gin.Context) {
 // @Produce json
 // @Param input body dtos.CreateProductRequest true "Product information"
 // @Success 201 {object} models.Product "Product created successfully"
-// @Failure 400 {object} dtos.ErrorResponse "Invalid input data"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid input data or category ID"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
 // @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can create products"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /products [post]
 func CreateProduct(c *gin.Context) {
 	var req dtos.CreateProductRequest
@@ -125,17 +179,21 @@ func CreateProduct(c *gin.Context) {
 
 	user := authUser.(models.User)
 
-	if !user.IsAdmin() {
+	if !user.HasPermission(models.PermissionProductsCreate) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized access, only admins can create products"})
 		return
 	}
 
+	if !validateProductCategory(c, req.CategoryID) {
+		return
+	}
+
 	product := models.Product{
 		Name:        req.Name,
 		Description: req.Description,
 		Price:       req.Price,
 		Stock:       req.Stock,
-		Category:    req.Category,
+		CategoryID:  req.CategoryID,
 	}
 
 	if err := db.DB.Create(&product).Error; err != nil {
@@ -157,12 +215,13 @@ func CreateProduct(c *gin.Context) {
 // @Param id path int true "Product ID"
 // @Param input body dtos.CreateProductRequest true "Product data to update"
 // @Success 200 {object} models.Product "Updated product details"
-// @Failure 400 {object} dtos.ErrorResponse "Invalid product ID or request payload"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid product ID, request payload or category ID"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
 // @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
 // @Failure 404 {object} dtos.ErrorResponse "Product not found"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /products/{id} [put]
 func UpdateProduct(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Param("id"))
@@ -179,7 +238,7 @@ func UpdateProduct(c *gin.Context) {
 
 	user := authUser.(models.User)
 
-	if !user.IsAdmin() {
+	if !user.HasPermission(models.PermissionProductsUpdate) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized access, only admins can update products"})
 		return
 	}
@@ -201,6 +260,10 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
+	if !validateProductCategory(c, req.CategoryID) {
+		return
+	}
+
 	if err := db.DB.Model(product).Updates(req).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update product"})
 		return
@@ -219,12 +282,13 @@ func UpdateProduct(c *gin.Context) {
 // @Param id path int true "Product ID"
 // @Param input body dtos.PatchProductRequest true "Product data to patch"
 // @Success 200 {object} models.Product "Updated product details"
-// @Failure 400 {object} dtos.ErrorResponse "Invalid product ID or request payload"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid product ID, request payload or category ID"
 // @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
 // @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can patch products"
 // @Failure 404 {object} dtos.ErrorResponse "Product not found"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /products/{id} [patch]
 func PatchProduct(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Param("id"))
@@ -241,7 +305,7 @@ func PatchProduct(c *gin.Context) {
 
 	user := authUser.(models.User)
 
-	if !user.IsAdmin() {
+	if !user.HasPermission(models.PermissionProductsUpdate) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized access, only admins can patch products"})
 		return
 	}
@@ -263,6 +327,10 @@ func PatchProduct(c *gin.Context) {
 		return
 	}
 
+	if req.CategoryID != 0 && !validateProductCategory(c, req.CategoryID) {
+		return
+	}
+
 	if err := db.DB.Model(&product).Updates(req).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
 		return
@@ -283,6 +351,7 @@ func PatchProduct(c *gin.Context) {
 // @Failure 404 {object} dtos.ErrorResponse "Product not found"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /products/{id} [delete]
 func DeleteProduct(c *gin.Context) {
 	productID, err := strconv.Atoi(c.Param("id"))
@@ -299,13 +368,13 @@ func DeleteProduct(c *gin.Context) {
 
 	user := authUser.(models.User)
 
-	if !user.IsAdmin() {
+	if !user.HasPermission(models.PermissionProductsDelete) {
 		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Unauthorized access, only admins can delete products"})
 		return
 	}
 
 	var product models.Product
-	result := db.DB.First(&product, productID)
+	result := db.DB.Preload("Images").First(&product, productID)
 	if result.Error != nil {
 		if result.Error.Error() == "record not found" {
 			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Product not found"})
@@ -320,5 +389,31 @@ func DeleteProduct(c *gin.Context) {
 		return
 	}
 
+	for _, productImage := range product.Images {
+		deleteProductImageFiles(c.Request.Context(), productImage)
+	}
+
 	c.Status(http.StatusNoContent)
 }
+
+// validateProductCategory writes a 400 response if there is no category with the given ID,
+// or a 500 response if the categories cannot be read.
+func validateProductCategory(c *gin.Context, categoryID uint) bool {
+	var count int64
+	if err := db.DB.Model(&models.Category{}).Where("id = ?", categoryID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve category"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Invalid category ID: %d", categoryID)})
+		return false
+	}
+
+	return true
+}
+
+// lockProduct locks the row of a product until tx ends, so that changes to its gallery,
+// options and variants are made one at a time.
+func lockProduct(tx *gorm.DB, productID uint) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Product{}, productID).Error
+}
@@ -0,0 +1,446 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/storage"
+	"github.com/cgzirim/ecommerce-api/thumbnail"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// IMAGE_MAX_PIXELS bounds the size of uploaded images once decoded, so that a small
+	// file cannot expand into an image that exhausts memory.
+	IMAGE_MAX_PIXELS = 25_000_000
+	// PRODUCT_MAX_IMAGES bounds the size of a product's gallery.
+	PRODUCT_MAX_IMAGES = 20
+)
+
+var (
+	// errGalleryFull marks uploads to a product that already has PRODUCT_MAX_IMAGES images.
+	errGalleryFull = errors.New("gallery full")
+	// errImageOrderMismatch marks reorders that do not list exactly the product's images.
+	errImageOrderMismatch = errors.New("image order mismatch")
+)
+
+var galleryFull = dtos.ErrorResponse{Error: fmt.Sprintf("Products can have at most %d images", PRODUCT_MAX_IMAGES)}
+
+// UploadProductImage godoc
+// @Summary Upload a product image
+// @Description Add an image to the end of a product's gallery. The image must be a JPEG, PNG or GIF file, which is recognised from its content rather than its name or declared type. Small, medium and large JPEG thumbnails of at most 160, 480 and 1024 pixels are generated from it.
+// @Tags Product
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param image formData file true "Image file"
+// @Param alt_text formData string false "Description of the image for screen readers"
+// @Success 201 {object} models.ProductImage "Image uploaded successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid product ID, missing or unreadable image, or gallery full"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
+// @Failure 404 {object} dtos.ErrorResponse "Product not found"
+// @Failure 413 {object} dtos.ErrorResponse "Image too large"
+// @Failure 415 {object} dtos.ErrorResponse "Image is not a JPEG, PNG or GIF file"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /products/{id}/images [post]
+func UploadProductImage(c *gin.Context) {
+	product, ok := findProductToUpdate(c)
+	if !ok {
+		return
+	}
+
+	// Leave room for the rest of the form, and check the file itself below
+	maxSize := imageMaxUploadSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+	tooLarge := dtos.ErrorResponse{Error: fmt.Sprintf("Image must be at most %d bytes", maxSize)}
+
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"image": "This field is required."}})
+		}
+		return
+	}
+
+	altText := strings.TrimSpace(c.PostForm("alt_text"))
+	if utf8.RuneCountInString(altText) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{"alt_text": "Value length must be less than or equal to 255"}})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to read image"})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to read image"})
+		return
+	}
+	if int64(len(content)) > maxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+		return
+	}
+
+	contentType := http.DetectContentType(content)
+	if _, ok := models.ProductImageExtensions[contentType]; !ok {
+		c.JSON(http.StatusUnsupportedMediaType, dtos.ErrorResponse{Error: "Image must be a JPEG, PNG or GIF file"})
+		return
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Image could not be read"})
+		return
+	}
+	if config.Width*config.Height > IMAGE_MAX_PIXELS {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Image must have at most %d pixels", IMAGE_MAX_PIXELS)})
+		return
+	}
+
+	// Fail early on a full gallery, before decoding or storing anything. The check is
+	// repeated when the image is added, since other uploads may finish in the meantime.
+	var imageCount int64
+	if err := db.DB.Model(&models.ProductImage{}).Where("product_id = ?", product.ID).Count(&imageCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to upload image"})
+		return
+	}
+	if imageCount >= PRODUCT_MAX_IMAGES {
+		c.JSON(http.StatusBadRequest, galleryFull)
+		return
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Image could not be read"})
+		return
+	}
+
+	thumbnails, err := thumbnail.Generate(decoded)
+	if err != nil {
+		log.Printf("Failed to generate thumbnails for product %v: %v", product.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to upload image"})
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(12)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to upload image"})
+		return
+	}
+
+	productImage := models.ProductImage{
+		ProductID:   product.ID,
+		AltText:     altText,
+		ContentType: contentType,
+		Width:       config.Width,
+		Height:      config.Height,
+		StorageKey:  fmt.Sprintf("products/%d/images/%s", product.ID, token),
+	}
+
+	ctx := c.Request.Context()
+	files := storage.Get()
+	err = files.Put(ctx, productImage.OriginalKey(), content, contentType)
+	for _, size := range thumbnail.Sizes {
+		if err == nil {
+			err = files.Put(ctx, productImage.ThumbnailKey(size.Name), thumbnails[size.Name], "image/jpeg")
+		}
+	}
+	if err == nil {
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := lockProduct(tx, product.ID); err != nil {
+				return err
+			}
+
+			var imageCount int64
+			if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", product.ID).Count(&imageCount).Error; err != nil {
+				return err
+			}
+			if imageCount >= PRODUCT_MAX_IMAGES {
+				return errGalleryFull
+			}
+
+			productImage.Position = int(imageCount)
+			return tx.Create(&productImage).Error
+		})
+	}
+	if err != nil {
+		deleteProductImageFiles(ctx, productImage)
+		if errors.Is(err, errGalleryFull) {
+			c.JSON(http.StatusBadRequest, galleryFull)
+			return
+		}
+		log.Printf("Failed to upload image for product %v: %v", product.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to upload image"})
+		return
+	}
+
+	setProductImageURLs(&productImage)
+	c.JSON(http.StatusCreated, productImage)
+}
+
+// UpdateProductImage godoc
+// @Summary Update a product image
+// @Description Change the alternative text of a product image.
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param image_id path int true "Image ID"
+// @Param input body dtos.UpdateProductImageRequest true "Image data"
+// @Success 200 {object} models.ProductImage "Updated image"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid ID or request payload"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
+// @Failure 404 {object} dtos.ErrorResponse "Product or image not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /products/{id}/images/{image_id} [patch]
+func UpdateProductImage(c *gin.Context) {
+	productImage, ok := findProductImageToUpdate(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.UpdateProductImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	productImage.AltText = strings.TrimSpace(req.AltText)
+	if err := db.DB.Model(&productImage).Update("alt_text", productImage.AltText).Error; err != nil {
+		log.Printf("Failed to update product image %v: %v", productImage.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update image"})
+		return
+	}
+
+	setProductImageURLs(&productImage)
+	c.JSON(http.StatusOK, productImage)
+}
+
+// ReorderProductImages godoc
+// @Summary Reorder the images of a product
+// @Description Set the order of a product's gallery. The request lists the IDs of all of the product's images, and the first one becomes the main image.
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param input body dtos.ReorderProductImagesRequest true "Image IDs in their new order"
+// @Success 200 {array} models.ProductImage "The reordered images"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid product ID, or the IDs are not those of the product's images"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
+// @Failure 404 {object} dtos.ErrorResponse "Product not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /products/{id}/images/order [put]
+func ReorderProductImages(c *gin.Context) {
+	product, ok := findProductToUpdate(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.ReorderProductImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, product.ID); err != nil {
+			return err
+		}
+
+		var imageIDs []uint
+		if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", product.ID).Pluck("id", &imageIDs).Error; err != nil {
+			return err
+		}
+
+		requested := slices.Clone(req.ImageIDs)
+		slices.Sort(requested)
+		slices.Sort(imageIDs)
+		if !slices.Equal(requested, imageIDs) {
+			return errImageOrderMismatch
+		}
+
+		for position, imageID := range req.ImageIDs {
+			if err := tx.Model(&models.ProductImage{}).Where("id = ?", imageID).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errImageOrderMismatch) {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "image_ids must list each of the product's images once"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to reorder the images of product %v: %v", product.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to reorder images"})
+		return
+	}
+
+	images := []models.ProductImage{}
+	if err := db.DB.Where("product_id = ?", product.ID).Order("position").Find(&images).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve images"})
+		return
+	}
+
+	for i := range images {
+		setProductImageURLs(&images[i])
+	}
+	c.JSON(http.StatusOK, images)
+}
+
+// DeleteProductImage godoc
+// @Summary Delete a product image
+// @Description Remove an image and its thumbnails from a product's gallery.
+// @Tags Product
+// @Param id path int true "Product ID"
+// @Param image_id path int true "Image ID"
+// @Success 204 "Image deleted successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid product or image ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
+// @Failure 404 {object} dtos.ErrorResponse "Product or image not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /products/{id}/images/{image_id} [delete]
+func DeleteProductImage(c *gin.Context) {
+	productImage, ok := findProductImageToUpdate(c)
+	if !ok {
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productImage.ProductID); err != nil {
+			return err
+		}
+
+		// Reload the position, which a concurrent reorder may have changed
+		if err := tx.First(&productImage, productImage.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&productImage).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ProductImage{}).
+			Where("product_id = ? AND position > ?", productImage.ProductID, productImage.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Image not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete product image %v: %v", productImage.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to delete image"})
+		return
+	}
+
+	deleteProductImageFiles(c.Request.Context(), productImage)
+	c.Status(http.StatusNoContent)
+}
+
+// findProductImageToUpdate loads the image named by the :image_id path parameter of the
+// product named by :id, like findProductToUpdate.
+func findProductImageToUpdate(c *gin.Context) (models.ProductImage, bool) {
+	var productImage models.ProductImage
+
+	product, ok := findProductToUpdate(c)
+	if !ok {
+		return productImage, false
+	}
+
+	imageID, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil || imageID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid image ID"})
+		return productImage, false
+	}
+
+	if err := db.DB.Where("product_id = ?", product.ID).Limit(1).Find(&productImage, imageID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve image"})
+		return productImage, false
+	}
+	if productImage.ID == 0 {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Image not found"})
+		return productImage, false
+	}
+
+	return productImage, true
+}
+
+// deleteProductImageFiles removes an image and its thumbnails from storage. Failures
+// are only logged, since the image is already gone from the gallery and the files are
+// not reachable from the API any more.
+func deleteProductImageFiles(ctx context.Context, productImage models.ProductImage) {
+	for _, key := range productImage.Keys() {
+		if err := storage.Get().Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete %s from storage: %v", key, err)
+		}
+	}
+}
+
+// setProductImageURLs fills in the URLs of an image and its thumbnails from the configured
+// storage.
+func setProductImageURLs(productImage *models.ProductImage) {
+	files := storage.Get()
+
+	productImage.URL = files.URL(productImage.OriginalKey())
+	productImage.Thumbnails = make(map[string]string, len(thumbnail.Sizes))
+	for _, size := range thumbnail.Sizes {
+		productImage.Thumbnails[size.Name] = files.URL(productImage.ThumbnailKey(size.Name))
+	}
+}
+
+// setProductGalleryURLs fills in the URLs of every image in a product's gallery.
+func setProductGalleryURLs(product *models.Product) {
+	for i := range product.Images {
+		setProductImageURLs(&product.Images[i])
+	}
+}
+
+// preloadProductImages loads the galleries of the products found by query in order.
+// Their URLs are not set; call setProductGalleryURLs before returning the products.
+func preloadProductImages(query *gorm.DB) *gorm.DB {
+	return query.Preload("Images", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("position")
+	})
+}
+
+// imageMaxUploadSize is the largest image file accepted, in bytes.
+func imageMaxUploadSize() int64 {
+	return int64(utils.GetEnvInt("IMAGE_MAX_UPLOAD_BYTES", 10<<20))
+}
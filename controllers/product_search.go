@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// SEARCH_MAX_QUERY_LENGTH and SEARCH_MAX_TERMS bound the work a single search can cause.
+	SEARCH_MAX_QUERY_LENGTH = 200
+	SEARCH_MAX_TERMS        = 8
+	// SEARCH_SNIPPET_LENGTH is the length in bytes of the description excerpt returned
+	// with each result when the database does not build one.
+	SEARCH_SNIPPET_LENGTH = 160
+)
+
+// Postgres wraps matches in these private use characters, which are replaced with <mark>
+// tags once the rest of the snippet has been HTML-escaped.
+const (
+	highlightStart = "\ue000"
+	highlightStop  = "\ue001"
+)
+
+// productSearchQuery whitelists the filters and sort keys of SearchProducts.
+var productSearchQuery = listQuery{
+	filters: map[string]listFilter{
+		"category":  {condition: "category_id IN ?", parse: parseCategoryParam},
+		"min_price": {condition: productMaxPrice + " >= ?", parse: parseFloatParam},
+		"max_price": {condition: productMinPrice + " <= ?", parse: parseFloatParam},
+		"in_stock":  {condition: inStockCondition},
+	},
+	sortColumns: map[string]string{
+		"relevance":  "rank",
+		"name":       "name",
+		"price":      productMinPrice,
+		"created_at": "created_at",
+	},
+	defaultSort: "-relevance",
+}
+
+// productSearch runs a search with one database's text search features.
+type productSearch struct {
+	// match returns a new query for the products matching the search.
+	match func() *gorm.DB
+	// columns selects the rank of each result, and its snippet if the database builds
+	// it, along with the product columns.
+	columns string
+	args    []interface{}
+	// fuzzy is set for searches that match similarly spelled words.
+	fuzzy bool
+}
+
+// SearchProducts godoc
+// @Summary Search products
+// @Description Search the catalog by name, category and description, ranking matches in the name highest. Each word matches words starting with it, and misspelled queries that match nothing fall back to similarly spelled product names and categories. Facets count the matching products per category, regardless of the other filters.
+// @Tags Product
+// @Produce json
+// @Param q query string true "Search query"
+// @Param page query int false "Page number" default(1)
+// @Param pageSize query int false "Number of results per page, lowered to 100 if larger" default(10)
+// @Param category query string false "Filter by category slug, including its subcategories"
+// @Param min_price query number false "Minimum price, which a product with variants meets if any variant does"
+// @Param max_price query number false "Maximum price, which a product with variants meets if any variant does"
+// @Param in_stock query bool false "Only products in stock, or with a variant in stock"
+// @Param sort query string false "Comma-separated sort keys, each prefixed with - for descending order. Keys: relevance, name, price, created_at. Products with variants sort by their cheapest variant" default(-relevance)
+// @Success 200 {object} dtos.ProductSearchResponse "Search results"
+// @Failure 400 {object} dtos.ErrorResponse "Missing or invalid query parameter"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /products/search [get]
+func SearchProducts(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "The q parameter is required"})
+		return
+	}
+	if len(q) > SEARCH_MAX_QUERY_LENGTH {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("The q parameter must be at most %d characters", SEARCH_MAX_QUERY_LENGTH)})
+		return
+	}
+
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "The q parameter must contain a letter or digit"})
+		return
+	}
+
+	searches := []productSearch{likeSearch(terms)}
+	if db.DB.Dialector.Name() == "postgres" {
+		searches = []productSearch{fullTextSearch(terms), trigramSearch(terms)}
+	}
+
+	// Fall back to the next search only if the previous one matched nothing at all, not
+	// just nothing that passes the filters
+	var search productSearch
+	for _, search = range searches {
+		var matches int64
+		if err := search.match().Count(&matches).Error; err != nil {
+			log.Printf("Failed to search products: %v", err)
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to search products"})
+			return
+		}
+		if matches > 0 {
+			break
+		}
+	}
+
+	query, options, ok := productSearchQuery.parse(c, search.match())
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("Failed to search products: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to search products"})
+		return
+	}
+
+	results := []dtos.ProductSearchResult{}
+	err := preloadProductImages(query.Select("products.*, "+search.columns, search.args...)).
+		Order(options.order).Limit(options.pageSize).Offset(options.offset()).
+		Find(&results).Error
+	if err != nil {
+		log.Printf("Failed to search products: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to search products"})
+		return
+	}
+
+	for i := range results {
+		setProductGalleryURLs(&results[i].Product)
+		if results[i].Snippet == "" {
+			results[i].Snippet = highlightSnippet(results[i].Description, terms)
+		} else {
+			results[i].Snippet = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").
+				Replace(html.EscapeString(results[i].Snippet))
+		}
+	}
+
+	facets, err := searchFacets(search)
+	if err != nil {
+		log.Printf("Failed to count product search facets: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to search products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.ProductSearchResponse{
+		Query:      q,
+		Fuzzy:      search.fuzzy,
+		Page:       options.page,
+		PageSize:   options.pageSize,
+		TotalCount: total,
+		TotalPages: int(math.Ceil(float64(total) / float64(options.pageSize))),
+		Results:    results,
+		Facets:     facets,
+	})
+}
+
+// searchFacets counts the products matching a search in each category, most matches first.
+func searchFacets(search productSearch) ([]dtos.CategoryFacet, error) {
+	var counts []struct {
+		CategoryID uint
+		Count      int64
+	}
+	err := search.match().Select("category_id, COUNT(*) AS count").Group("category_id").Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	categoryIDs := make([]uint, len(counts))
+	for i, count := range counts {
+		categoryIDs[i] = count.CategoryID
+	}
+
+	var categories []models.Category
+	if err := db.DB.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	names := map[uint]models.Category{}
+	for _, category := range categories {
+		names[category.ID] = category
+	}
+
+	facets := make([]dtos.CategoryFacet, len(counts))
+	for i, count := range counts {
+		category := names[count.CategoryID]
+		facets[i] = dtos.CategoryFacet{Category: category.Name, Slug: category.Slug, Count: count.Count}
+	}
+
+	slices.SortFunc(facets, func(a, b dtos.CategoryFacet) int {
+		if a.Count != b.Count {
+			return int(b.Count - a.Count)
+		}
+		return strings.Compare(a.Category, b.Category)
+	})
+	return facets, nil
+}
+
+// searchTerms splits a search query into lowercase words of letters and digits, dropping
+// everything else so that no user input reaches the text search query syntax.
+func searchTerms(q string) []string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	var terms []string
+	for _, word := range words {
+		if len(terms) == SEARCH_MAX_TERMS {
+			break
+		}
+		if !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// fullTextSearch matches products whose search_vector contains every term, treating
+// each term as a prefix. Postgres ranks the results and builds the snippets.
+func fullTextSearch(terms []string) productSearch {
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	tsquery := strings.Join(prefixes, " & ")
+	headlineOptions := fmt.Sprintf("StartSel=%s, StopSel=%s, MinWords=15, MaxWords=35", highlightStart, highlightStop)
+
+	return productSearch{
+		match: func() *gorm.DB {
+			return db.DB.Table("products, to_tsquery('english', ?) AS search_query", tsquery).
+				Where("products.search_vector @@ search_query")
+		},
+		columns: "ts_rank(products.search_vector, search_query) AS rank, " +
+			"ts_headline('english', products.description, search_query, ?) AS snippet",
+		args: []interface{}{headlineOptions},
+	}
+}
+
+// trigramSearch matches products whose name or category name contains a word spelled
+// like the query, for queries with typos that full-text search finds nothing for.
+func trigramSearch(terms []string) productSearch {
+	phrase := strings.Join(terms, " ")
+
+	return productSearch{
+		match: func() *gorm.DB {
+			return db.DB.Model(&models.Product{}).
+				Where("? <% name OR category_id IN (SELECT id FROM categories WHERE ? <% name)", phrase, phrase)
+		},
+		columns: "GREATEST(word_similarity(?, name), " +
+			"word_similarity(?, (SELECT name FROM categories WHERE categories.id = products.category_id)) / 2) AS rank",
+		args:  []interface{}{phrase, phrase},
+		fuzzy: true,
+	}
+}
+
+// likeSearch is a simpler search for databases without full-text search, such as the
+// SQLite database used in tests. Every term must appear somewhere in the name, category
+// name or description, and the rank weighs the fields the way the full-text search does.
+func likeSearch(terms []string) productSearch {
+	var conditions, rank []string
+	var conditionArgs, rankArgs []interface{}
+	for _, term := range terms {
+		pattern := "%" + term + "%"
+		conditions = append(conditions, "(LOWER(name) LIKE ? OR "+likeCategoryCondition+" OR LOWER(description) LIKE ?)")
+		conditionArgs = append(conditionArgs, pattern, pattern, pattern)
+
+		rank = append(rank, "CASE WHEN LOWER(name) LIKE ? THEN 1.0 WHEN "+likeCategoryCondition+" THEN 0.4 ELSE 0.2 END")
+		rankArgs = append(rankArgs, pattern, pattern)
+	}
+
+	return productSearch{
+		match: func() *gorm.DB {
+			return db.DB.Model(&models.Product{}).Where(strings.Join(conditions, " AND "), conditionArgs...)
+		},
+		columns: fmt.Sprintf("(%s) / %d AS rank", strings.Join(rank, " + "), len(terms)),
+		args:    rankArgs,
+	}
+}
+
+// likeCategoryCondition matches products whose category name contains a pattern.
+const likeCategoryCondition = "category_id IN (SELECT id FROM categories WHERE LOWER(categories.name) LIKE ?)"
+
+// highlightSnippet returns an HTML-escaped excerpt of text around the first of the terms
+// it contains, with the terms wrapped in <mark> tags.
+func highlightSnippet(text string, terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	pattern := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+
+	start := 0
+	if match := pattern.FindStringIndex(text); match != nil && match[0] > SEARCH_SNIPPET_LENGTH/3 {
+		// Start at the beginning of a word shortly before the match
+		start = match[0] - SEARCH_SNIPPET_LENGTH/3
+		if space := strings.IndexByte(text[start:match[0]], ' '); space >= 0 {
+			start += space + 1
+		}
+		for !utf8.RuneStart(text[start]) {
+			start++
+		}
+	}
+
+	end := len(text)
+	if end-start > SEARCH_SNIPPET_LENGTH {
+		end = start + SEARCH_SNIPPET_LENGTH
+		for !utf8.RuneStart(text[end]) {
+			end--
+		}
+		if space := strings.LastIndexByte(text[start:end], ' '); space > 0 {
+			end = start + space
+		}
+	}
+
+	excerpt := text[start:end]
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString("…")
+	}
+
+	last := 0
+	for _, match := range pattern.FindAllStringIndex(excerpt, -1) {
+		snippet.WriteString(html.EscapeString(excerpt[last:match[0]]))
+		snippet.WriteString("<mark>" + html.EscapeString(excerpt[match[0]:match[1]]) + "</mark>")
+		last = match[1]
+	}
+	snippet.WriteString(html.EscapeString(excerpt[last:]))
+
+	if end < len(text) {
+		snippet.WriteString("…")
+	}
+	return snippet.String()
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSearchProducts(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.Category{}, &models.Product{}, &models.ProductVariant{}, &models.ProductImage{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	clothing := models.Category{Name: "Clothing", Slug: "clothing"}
+	accessories := models.Category{Name: "Accessories", Slug: "accessories"}
+	home := models.Category{Name: "Home", Slug: "home"}
+	for _, category := range []*models.Category{&clothing, &accessories, &home} {
+		mockDB.Create(category)
+	}
+
+	for _, product := range []models.Product{
+		{Name: "Leather Jacket", CategoryID: clothing.ID, Description: "A warm jacket made of <b>genuine</b> leather.", Price: 120, Stock: 2},
+		{Name: "Leather Wallet", CategoryID: accessories.ID, Description: "Slim wallet with six card slots.", Price: 40, Stock: 0},
+		{Name: "Rain Coat", CategoryID: clothing.ID, Description: "Keeps you dry. Pairs well with a leather belt.", Price: 80, Stock: 5},
+		{Name: "Desk Lamp", CategoryID: home.ID, Description: "An adjustable lamp.", Price: 25, Stock: 9},
+	} {
+		mockDB.Create(&product)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/products/search", SearchProducts)
+
+	search := func(query url.Values) (int, dtos.ProductSearchResponse) {
+		req, _ := http.NewRequest("GET", "/products/search?"+query.Encode(), nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var response dtos.ProductSearchResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+
+	names := func(response dtos.ProductSearchResponse) []string {
+		var names []string
+		for _, result := range response.Results {
+			names = append(names, result.Name)
+		}
+		return names
+	}
+
+	t.Run("Ranks name matches above description matches", func(t *testing.T) {
+		code, response := search(url.Values{"q": {"LEATHER"}})
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"Leather Jacket", "Leather Wallet", "Rain Coat"}, names(response))
+		assert.Equal(t, int64(3), response.TotalCount)
+		assert.Greater(t, response.Results[0].Rank, response.Results[2].Rank)
+		assert.False(t, response.Fuzzy)
+	})
+
+	t.Run("Matches every word by prefix", func(t *testing.T) {
+		_, response := search(url.Values{"q": {"leath jack"}})
+		assert.Equal(t, []string{"Leather Jacket"}, names(response))
+	})
+
+	t.Run("Highlights matches in an escaped snippet", func(t *testing.T) {
+		_, response := search(url.Values{"q": {"genuine"}})
+		assert.Len(t, response.Results, 1)
+		assert.Equal(t, "A warm jacket made of &lt;b&gt;<mark>genuine</mark>&lt;/b&gt; leather.", response.Results[0].Snippet)
+	})
+
+	t.Run("Counts matches by category regardless of filters", func(t *testing.T) {
+		_, response := search(url.Values{"q": {"leather"}, "category": {"clothing"}, "in_stock": {"true"}})
+		assert.Equal(t, []string{"Leather Jacket", "Rain Coat"}, names(response))
+		assert.Equal(t, []dtos.CategoryFacet{
+			{Category: "Clothing", Slug: "clothing", Count: 2},
+			{Category: "Accessories", Slug: "accessories", Count: 1},
+		}, response.Facets)
+
+		_, response = search(url.Values{"q": {"leather"}, "sort": {"price"}, "pageSize": {"1"}, "page": {"2"}})
+		assert.Equal(t, []string{"Rain Coat"}, names(response))
+		assert.Equal(t, 3, response.TotalPages)
+	})
+
+	t.Run("Matches category names", func(t *testing.T) {
+		_, response := search(url.Values{"q": {"accessories"}})
+		assert.Equal(t, []string{"Leather Wallet"}, names(response))
+	})
+
+	t.Run("Rejects invalid queries", func(t *testing.T) {
+		for _, query := range []url.Values{
+			{},
+			{"q": {"  "}},
+			{"q": {"&|!:*"}},
+			{"q": {"leather"}, "sort": {"rank"}},
+			{"q": {"leather"}, "fields": {"name"}},
+		} {
+			code, _ := search(query)
+			assert.Equal(t, http.StatusBadRequest, code, query.Encode())
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/cgzirim/ecommerce-api/db"
 	"github.com/cgzirim/ecommerce-api/dtos"
@@ -18,7 +19,7 @@ import (
 
 func TestCreateProduct(t *testing.T) {
 	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	mockDB.AutoMigrate(&models.Product{}, &models.User{})
+	mockDB.AutoMigrate(&models.Category{}, &models.Product{}, &models.ProductVariant{}, &models.ProductImage{}, &models.User{})
 
 	originalDB := db.DB
 	db.SetMockDB(mockDB)
@@ -27,6 +28,9 @@ func TestCreateProduct(t *testing.T) {
 	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: "password"}
 	mockDB.Create(&admin)
 
+	category := models.Category{Name: "Category A", Slug: "category-a"}
+	mockDB.Create(&category)
+
 	t.Run("Successfully creates product", func(t *testing.T) {
 		gin.SetMode(gin.TestMode)
 
@@ -41,7 +45,7 @@ func TestCreateProduct(t *testing.T) {
 			Description: "Description of Product A",
 			Price:       10.0,
 			Stock:       100,
-			Category:    "Category A",
+			CategoryID:  category.ID,
 		}
 		body, _ := json.Marshal(productRequest)
 		req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(body))
@@ -59,7 +63,64 @@ func TestCreateProduct(t *testing.T) {
 		assert.Equal(t, productRequest.Description, createdProduct.Description)
 		assert.Equal(t, productRequest.Price, createdProduct.Price)
 		assert.Equal(t, productRequest.Stock, createdProduct.Stock)
-		assert.Equal(t, productRequest.Category, createdProduct.Category)
+		assert.Equal(t, productRequest.CategoryID, createdProduct.CategoryID)
+	})
+
+	t.Run("Fails with an unknown category", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/products", func(c *gin.Context) {
+			c.Set("user", admin)
+			CreateProduct(c)
+		})
+
+		productRequest := dtos.CreateProductRequest{
+			Name:        "Product A",
+			Description: "Description of Product A",
+			Price:       10.0,
+			Stock:       100,
+			CategoryID:  category.ID + 1,
+		}
+		body, _ := json.Marshal(productRequest)
+		req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var response dtos.ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.Equal(t, "Invalid category ID: 2", response.Error)
+	})
+
+	t.Run("Fails when the categories cannot be read", func(t *testing.T) {
+		mockDB.Migrator().DropTable(&models.Category{})
+		defer mockDB.AutoMigrate(&models.Category{})
+
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/products", func(c *gin.Context) {
+			c.Set("user", admin)
+			CreateProduct(c)
+		})
+
+		productRequest := dtos.CreateProductRequest{
+			Name:        "Product A",
+			Description: "Description of Product A",
+			Price:       10.0,
+			Stock:       100,
+			CategoryID:  category.ID,
+		}
+		body, _ := json.Marshal(productRequest)
+		req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 
 	t.Run("Fails with invalid input data", func(t *testing.T) {
@@ -95,7 +156,7 @@ func TestCreateProduct(t *testing.T) {
 			Description: "Description of Product A",
 			Price:       10.0,
 			Stock:       100,
-			Category:    "Category A",
+			CategoryID:  category.ID,
 		}
 		body, _ := json.Marshal(productRequest)
 		req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(body))
@@ -128,7 +189,7 @@ func TestCreateProduct(t *testing.T) {
 			Description: "Description of Product A",
 			Price:       10.0,
 			Stock:       100,
-			Category:    "Category A",
+			CategoryID:  category.ID,
 		}
 		body, _ := json.Marshal(productRequest)
 		req, _ := http.NewRequest("POST", "/products", bytes.NewBuffer(body))
@@ -144,3 +205,98 @@ func TestCreateProduct(t *testing.T) {
 		assert.Equal(t, "Unauthorized access, only admins can create products", response.Error)
 	})
 }
+
+func TestListProducts(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.Category{}, &models.Product{}, &models.ProductVariant{}, &models.ProductImage{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	clothing := models.Category{Name: "Clothing", Slug: "clothing"}
+	mockDB.Create(&clothing)
+	home := models.Category{Name: "Home", Slug: "home"}
+	mockDB.Create(&home)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, product := range []models.Product{
+		{Name: "Shirt", CategoryID: clothing.ID, Price: 20, Stock: 5},
+		{Name: "Jacket", CategoryID: clothing.ID, Price: 80, Stock: 0},
+		{Name: "Socks", CategoryID: clothing.ID, Price: 5, Stock: 50},
+		{Name: "Lamp", CategoryID: home.ID, Price: 20, Stock: 3},
+	} {
+		product.CreatedAt = created
+		mockDB.Create(&product)
+		created = created.AddDate(0, 1, 0)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/products", ListProducts)
+
+	list := func(query string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("GET", "/products?"+query, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+
+	names := func(response map[string]interface{}) []string {
+		var names []string
+		for _, product := range response["products"].([]interface{}) {
+			names = append(names, product.(map[string]interface{})["name"].(string))
+		}
+		return names
+	}
+
+	t.Run("Filters products", func(t *testing.T) {
+		code, response := list("category=clothing&min_price=10&in_stock=true")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"Shirt"}, names(response))
+		assert.Equal(t, float64(1), response["total_count"])
+
+		_, response = list("max_price=20&created_after=2024-01-15")
+		assert.Equal(t, []string{"Socks", "Lamp"}, names(response))
+	})
+
+	t.Run("Sorts by several keys", func(t *testing.T) {
+		code, response := list("sort=-price,name")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"Jacket", "Lamp", "Shirt", "Socks"}, names(response))
+
+		_, response = list("")
+		assert.Equal(t, []string{"Shirt", "Jacket", "Socks", "Lamp"}, names(response))
+	})
+
+	t.Run("Returns only the requested fields", func(t *testing.T) {
+		code, response := list("fields=name,price&pageSize=1")
+		assert.Equal(t, http.StatusOK, code)
+
+		products := response["products"].([]interface{})
+		assert.Len(t, products, 1)
+		assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "Shirt", "price": float64(20)}, products[0])
+		assert.Equal(t, float64(4), response["total_pages"])
+	})
+
+	t.Run("Lowers large page sizes to the limit", func(t *testing.T) {
+		code, response := list("pageSize=500")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, float64(listMaxPageSize), response["page_size"])
+		assert.Len(t, response["products"], 4)
+	})
+
+	t.Run("Rejects parameters outside the whitelist", func(t *testing.T) {
+		for _, query := range []string{
+			"sort=password", "sort=name%3BDROP%20TABLE%20products", "sort=name,-name", "fields=name,secret",
+			"min_price=cheap", "in_stock=maybe", "created_after=yesterday", "pageSize=0",
+		} {
+			code, response := list(query)
+			assert.Equal(t, http.StatusBadRequest, code, query)
+			assert.NotEmpty(t, response["error"], query)
+		}
+	})
+}
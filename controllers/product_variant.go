@@ -0,0 +1,568 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PRODUCT_MAX_VARIANTS bounds the number of combinations of option values a product can
+// have, and with it the size of the variant matrix.
+const PRODUCT_MAX_VARIANTS = 100
+
+var skuPattern = regexp.MustCompile(`^[A-Z0-9]+([-_.][A-Z0-9]+)*$`)
+
+var (
+	// errVariantConflict marks option changes that would leave variants without a value
+	// for every option.
+	errVariantConflict = errors.New("variant conflict")
+	// errInvalidVariants marks variant matrices that cannot be generated as requested.
+	errInvalidVariants = errors.New("invalid variants")
+)
+
+// SetProductOptions godoc
+// @Summary Set the options of a product
+// @Description Replace the options of a product, such as its sizes and colours, and the values each comes in. Options and values are matched to the existing ones by name, so renaming them is not possible, but reordering them is. While the product has variants, options cannot be added or removed, and values used by a variant cannot be removed.
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param input body dtos.SetProductOptionsRequest true "Product options"
+// @Success 200 {array} models.ProductOption "The product's options"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid product ID or request payload, or duplicate option names or values"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
+// @Failure 404 {object} dtos.ErrorResponse "Product not found"
+// @Failure 409 {object} dtos.ErrorResponse "The change would leave variants without a value for each option"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /products/{id}/options [put]
+func SetProductOptions(c *gin.Context) {
+	product, ok := findProductToUpdate(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.SetProductOptionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	combinations := 1
+	seenOptions := map[string]bool{}
+	for i, option := range req.Options {
+		option.Name = strings.TrimSpace(option.Name)
+		if option.Name == "" || seenOptions[strings.ToLower(option.Name)] {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Option names must be unique and not blank: %q", option.Name)})
+			return
+		}
+		seenOptions[strings.ToLower(option.Name)] = true
+
+		seenValues := map[string]bool{}
+		for j, value := range option.Values {
+			value = strings.TrimSpace(value)
+			if value == "" || seenValues[strings.ToLower(value)] {
+				c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
+					Error: fmt.Sprintf("Values of option %s must be unique and not blank: %q", option.Name, value),
+				})
+				return
+			}
+			seenValues[strings.ToLower(value)] = true
+			option.Values[j] = value
+		}
+
+		req.Options[i] = option
+		combinations *= len(option.Values)
+	}
+
+	if combinations > PRODUCT_MAX_VARIANTS {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{
+			Error: fmt.Sprintf("The options allow %d combinations, at most %d are allowed", combinations, PRODUCT_MAX_VARIANTS),
+		})
+		return
+	}
+
+	var conflict string
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Keep variants from being generated from the options while they change
+		if err := lockProduct(tx, product.ID); err != nil {
+			return err
+		}
+
+		var existing []models.ProductOption
+		if err := tx.Preload("Values").Where("product_id = ?", product.ID).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		var variantCount int64
+		if err := tx.Model(&models.ProductVariant{}).Where("product_id = ?", product.ID).Count(&variantCount).Error; err != nil {
+			return err
+		}
+
+		existingOptions := map[string]models.ProductOption{}
+		for _, option := range existing {
+			existingOptions[strings.ToLower(option.Name)] = option
+		}
+
+		if variantCount > 0 && len(req.Options) != len(existing) {
+			conflict = "Options cannot be added or removed while the product has variants"
+			return errVariantConflict
+		}
+
+		for position, requested := range req.Options {
+			option, found := existingOptions[strings.ToLower(requested.Name)]
+			if !found && variantCount > 0 {
+				conflict = "Options cannot be added or removed while the product has variants"
+				return errVariantConflict
+			}
+			delete(existingOptions, strings.ToLower(requested.Name))
+
+			option.ProductID = product.ID
+			option.Name = requested.Name
+			option.Position = position
+			if err := tx.Omit("Values").Save(&option).Error; err != nil {
+				return err
+			}
+
+			existingValues := map[string]models.ProductOptionValue{}
+			for _, value := range option.Values {
+				existingValues[strings.ToLower(value.Value)] = value
+			}
+
+			for valuePosition, requestedValue := range requested.Values {
+				value := existingValues[strings.ToLower(requestedValue)]
+				delete(existingValues, strings.ToLower(requestedValue))
+
+				value.OptionID = option.ID
+				value.Value = requestedValue
+				value.Position = valuePosition
+				if err := tx.Save(&value).Error; err != nil {
+					return err
+				}
+			}
+
+			for _, removed := range existingValues {
+				var uses int64
+				err := tx.Table("product_variant_option_values").Where("product_option_value_id = ?", removed.ID).Count(&uses).Error
+				if err != nil {
+					return err
+				}
+				if uses > 0 {
+					conflict = fmt.Sprintf("Value %s of option %s is used by %d variants", removed.Value, option.Name, uses)
+					return errVariantConflict
+				}
+
+				if err := tx.Delete(&removed).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, removed := range existingOptions {
+			if err := tx.Where("option_id = ?", removed.ID).Delete(&models.ProductOptionValue{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&removed).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, errVariantConflict) {
+		c.JSON(http.StatusConflict, dtos.ErrorResponse{Error: conflict})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to set the options of product %v: %v", product.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update product options"})
+		return
+	}
+
+	if err := preloadProductVariants(db.DB).First(&product, product.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve product options"})
+		return
+	}
+
+	c.JSON(http.StatusOK, product.Options)
+}
+
+// GenerateProductVariants godoc
+// @Summary Generate the variants of a product
+// @Description Create a variant for every combination of option values that does not have one yet. Existing variants are left unchanged. SKUs are built from the prefix and the values, e.g. OXFORD-M-BLUE, and the prefix defaults to the product name.
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param input body dtos.GenerateProductVariantsRequest false "Defaults for the new variants"
+// @Success 201 {array} models.ProductVariant "The variants created"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid product ID or request payload, product without options, or SKUs already in use"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
+// @Failure 404 {object} dtos.ErrorResponse "Product not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /products/{id}/variants/generate [post]
+func GenerateProductVariants(c *gin.Context) {
+	product, ok := findProductToUpdate(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional
+	var req dtos.GenerateProductVariantsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	var invalid string
+	variants := []models.ProductVariant{}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Keep the options from changing while variants are generated from them
+		if err := lockProduct(tx, product.ID); err != nil {
+			return err
+		}
+
+		if err := preloadProductVariants(tx).First(&product, product.ID).Error; err != nil {
+			return err
+		}
+
+		if len(product.Options) == 0 {
+			invalid = "Product has no options to generate variants from"
+			return errInvalidVariants
+		}
+
+		prefix := strings.ToUpper(models.Slugify(req.SKUPrefix))
+		if prefix == "" {
+			prefix = strings.ToUpper(models.Slugify(product.Name))
+		}
+		if prefix == "" {
+			prefix = fmt.Sprintf("P%d", product.ID)
+		}
+
+		price := req.Price
+		if price == 0 {
+			price = product.Price
+		}
+
+		existing := map[string]bool{}
+		for _, variant := range product.Variants {
+			existing[variantKey(variant.OptionValues)] = true
+		}
+
+		skus := map[string]bool{}
+		for _, values := range optionCombinations(product.Options) {
+			if existing[variantKey(values)] {
+				continue
+			}
+
+			parts := []string{prefix}
+			for _, value := range values {
+				parts = append(parts, strings.ToUpper(models.Slugify(value.Value)))
+			}
+			sku := strings.Join(parts, "-")
+
+			if skus[sku] || !skuPattern.MatchString(sku) {
+				invalid = fmt.Sprintf("Option values do not make a unique SKU: %s", sku)
+				return errInvalidVariants
+			}
+			skus[sku] = true
+
+			variants = append(variants, models.ProductVariant{
+				ProductID:    product.ID,
+				SKU:          sku,
+				Price:        price,
+				Stock:        req.Stock,
+				Weight:       req.Weight,
+				OptionValues: values,
+			})
+		}
+
+		if len(variants) == 0 {
+			return nil
+		}
+
+		var taken []string
+		skuList := make([]string, 0, len(skus))
+		for sku := range skus {
+			skuList = append(skuList, sku)
+		}
+		if err := tx.Model(&models.ProductVariant{}).Where("sku IN ?", skuList).Pluck("sku", &taken).Error; err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			invalid = fmt.Sprintf("SKU already exists: %s, use a different sku_prefix", taken[0])
+			return errInvalidVariants
+		}
+
+		return tx.Create(&variants).Error
+	})
+	switch {
+	case errors.Is(err, errInvalidVariants):
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: invalid})
+		return
+	case isDuplicateSKU(err):
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "SKU already exists, use a different sku_prefix"})
+		return
+	case err != nil:
+		log.Printf("Failed to generate the variants of product %v: %v", product.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to generate variants"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, variants)
+}
+
+// UpdateProductVariant godoc
+// @Summary Update a product variant
+// @Description Update the SKU, price, stock or weight of a variant. SKUs are stored in upper case.
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param id path int true "Product ID"
+// @Param variant_id path int true "Variant ID"
+// @Param input body dtos.PatchProductVariantRequest true "Variant data to patch"
+// @Success 200 {object} models.ProductVariant "Updated variant"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid ID or request payload, or SKU already in use"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
+// @Failure 404 {object} dtos.ErrorResponse "Product or variant not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /products/{id}/variants/{variant_id} [patch]
+func UpdateProductVariant(c *gin.Context) {
+	variant, ok := findProductVariantToUpdate(c)
+	if !ok {
+		return
+	}
+
+	var req dtos.PatchProductVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.SKU != "" {
+		sku := strings.ToUpper(strings.TrimSpace(req.SKU))
+		if !skuPattern.MatchString(sku) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"sku": "Value must be letters and digits separated by single hyphens, underscores or dots.",
+			}})
+			return
+		}
+
+		var existing int64
+		if err := db.DB.Model(&models.ProductVariant{}).Where("sku = ? AND id <> ?", sku, variant.ID).Count(&existing).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update variant"})
+			return
+		}
+		if existing > 0 {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Variant with this SKU already exists"})
+			return
+		}
+		updates["sku"] = sku
+	}
+	if req.Price != 0 {
+		updates["price"] = req.Price
+	}
+	if req.Stock != nil {
+		updates["stock"] = *req.Stock
+	}
+	if req.Weight != nil {
+		updates["weight"] = *req.Weight
+	}
+
+	if err := db.DB.Model(&variant).Updates(updates).Error; err != nil {
+		if isDuplicateSKU(err) {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Variant with this SKU already exists"})
+			return
+		}
+		log.Printf("Failed to update product variant %v: %v", variant.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update variant"})
+		return
+	}
+
+	if err := db.DB.Preload("OptionValues").First(&variant, variant.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve variant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeleteProductVariant godoc
+// @Summary Delete a product variant
+// @Description Delete a variant that has never been ordered. Set the stock of ordered variants to 0 instead.
+// @Tags Product
+// @Param id path int true "Product ID"
+// @Param variant_id path int true "Variant ID"
+// @Success 204 "Variant deleted successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid product or variant ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Unauthorized access, only admins can update products"
+// @Failure 404 {object} dtos.ErrorResponse "Product or variant not found"
+// @Failure 409 {object} dtos.ErrorResponse "Variant has been ordered"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /products/{id}/variants/{variant_id} [delete]
+func DeleteProductVariant(c *gin.Context) {
+	variant, ok := findProductVariantToUpdate(c)
+	if !ok {
+		return
+	}
+
+	var orderItems int64
+	if err := db.DB.Model(&models.OrderItem{}).Where("variant_id = ?", variant.ID).Count(&orderItems).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to delete variant"})
+		return
+	}
+	if orderItems > 0 {
+		c.JSON(http.StatusConflict, dtos.ErrorResponse{Error: "Variant has been ordered and cannot be deleted, set its stock to 0 instead"})
+		return
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&variant).Association("OptionValues").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&variant).Error
+	})
+	if err != nil {
+		log.Printf("Failed to delete product variant %v: %v", variant.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to delete variant"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// findProductToUpdate loads the product named by the :id path parameter for a user with
+// the products:update permission, writing an error response otherwise.
+func findProductToUpdate(c *gin.Context) (models.Product, bool) {
+	var product models.Product
+
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || productID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid product ID"})
+		return product, false
+	}
+
+	authUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+		return product, false
+	}
+
+	user := authUser.(models.User)
+
+	if !user.HasPermission(models.PermissionProductsUpdate) {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "Unauthorized access, only admins can update products"})
+		return product, false
+	}
+
+	result := db.DB.First(&product, productID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Product not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return product, false
+	}
+
+	return product, true
+}
+
+// findProductVariantToUpdate loads the variant named by the :variant_id path parameter
+// of the product named by :id, like findProductToUpdate.
+func findProductVariantToUpdate(c *gin.Context) (models.ProductVariant, bool) {
+	var variant models.ProductVariant
+
+	product, ok := findProductToUpdate(c)
+	if !ok {
+		return variant, false
+	}
+
+	variantID, err := strconv.Atoi(c.Param("variant_id"))
+	if err != nil || variantID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid variant ID"})
+		return variant, false
+	}
+
+	if err := db.DB.Where("product_id = ?", product.ID).Limit(1).Find(&variant, variantID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve variant"})
+		return variant, false
+	}
+	if variant.ID == 0 {
+		c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Variant not found"})
+		return variant, false
+	}
+
+	return variant, true
+}
+
+// preloadProductVariants loads the options of the products found by query in order,
+// along with their variants.
+func preloadProductVariants(query *gorm.DB) *gorm.DB {
+	byPosition := func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("position")
+	}
+
+	return query.Preload("Options", byPosition).Preload("Options.Values", byPosition).
+		Preload("Variants", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("id")
+		}).
+		Preload("Variants.OptionValues")
+}
+
+// optionCombinations returns every combination of one value of each option, varying
+// the values of the last option fastest.
+func optionCombinations(options []models.ProductOption) [][]models.ProductOptionValue {
+	combinations := [][]models.ProductOptionValue{{}}
+	for _, option := range options {
+		var next [][]models.ProductOptionValue
+		for _, combination := range combinations {
+			for _, value := range option.Values {
+				next = append(next, append(combination[:len(combination):len(combination)], value))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// isDuplicateSKU reports whether err is a violation of the unique index on variant SKUs,
+// which happens when a concurrent request takes a SKU after it was checked.
+func isDuplicateSKU(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), `duplicate key value violates unique constraint "idx_product_variants_sku"`) ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed: product_variants.sku"))
+}
+
+// variantKey identifies a combination of option values regardless of their order.
+func variantKey(values []models.ProductOptionValue) string {
+	ids := make([]int, len(values))
+	for i, value := range values {
+		ids[i] = int(value.ID)
+	}
+	slices.Sort(ids)
+	return fmt.Sprint(ids)
+}
@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestProductVariants(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Category{}, &models.Address{}, &models.Product{}, &models.ProductImage{}, &models.ProductOption{}, &models.ProductOptionValue{},
+		&models.ProductVariant{}, &models.Order{}, &models.OrderItem{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: "password"}
+	mockDB.Create(&admin)
+
+	customer := models.User{Email: "customer@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: "password"}
+	mockDB.Create(&customer)
+
+	address := models.Address{FirstName: "John", LastName: "Doe", City: "Berlin", Country: "DE", ZipCode: "10115", StreetAddress: "Street 1", UserID: customer.ID}
+	mockDB.Create(&address)
+
+	product := models.Product{Name: "Oxford Shirt", Price: 40, Stock: 1}
+	mockDB.Create(&product)
+
+	newRouter := func(user models.User) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+		})
+
+		router.GET("/products", ListProducts)
+		router.GET("/products/:id", GetProductByID)
+		router.PUT("/products/:id/options", SetProductOptions)
+		router.POST("/products/:id/variants/generate", GenerateProductVariants)
+		router.PATCH("/products/:id/variants/:variant_id", UpdateProductVariant)
+		router.DELETE("/products/:id/variants/:variant_id", DeleteProductVariant)
+		router.POST("/orders", CreateOrder)
+		return router
+	}
+
+	router := newRouter(admin)
+
+	request := func(router *gin.Engine, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	optionsPath := fmt.Sprintf("/products/%d/options", product.ID)
+	generatePath := fmt.Sprintf("/products/%d/variants/generate", product.ID)
+
+	var variants []models.ProductVariant
+
+	t.Run("Generates a variant for each combination of option values", func(t *testing.T) {
+		rec := request(router, "PUT", optionsPath, dtos.SetProductOptionsRequest{Options: []dtos.ProductOptionRequest{
+			{Name: "Size", Values: []string{"M", "L"}},
+			{Name: "Colour", Values: []string{"Blue", "White"}},
+		}})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = request(router, "POST", generatePath, nil)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		json.Unmarshal(rec.Body.Bytes(), &variants)
+		var skus []string
+		for _, variant := range variants {
+			skus = append(skus, variant.SKU)
+			assert.Equal(t, product.Price, variant.Price)
+			assert.Len(t, variant.OptionValues, 2)
+		}
+		assert.Equal(t, []string{"OXFORD-SHIRT-M-BLUE", "OXFORD-SHIRT-M-WHITE", "OXFORD-SHIRT-L-BLUE", "OXFORD-SHIRT-L-WHITE"}, skus)
+	})
+
+	t.Run("Only generates the missing variants", func(t *testing.T) {
+		rec := request(router, "PUT", optionsPath, dtos.SetProductOptionsRequest{Options: []dtos.ProductOptionRequest{
+			{Name: "Size", Values: []string{"M", "L", "XL"}},
+			{Name: "colour", Values: []string{"blue", "White"}},
+		}})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		rec = request(router, "POST", generatePath, dtos.GenerateProductVariantsRequest{SKUPrefix: "ox", Price: 45, Stock: 3})
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var created []models.ProductVariant
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		assert.Len(t, created, 2)
+		assert.Equal(t, "OX-XL-BLUE", created[0].SKU)
+		assert.Equal(t, 45.0, created[0].Price)
+		assert.Equal(t, 3, created[0].Stock)
+
+		rec = request(router, "GET", fmt.Sprintf("/products/%d", product.ID), nil)
+		var loaded models.Product
+		json.Unmarshal(rec.Body.Bytes(), &loaded)
+		assert.Len(t, loaded.Variants, 6)
+		assert.Equal(t, "colour", loaded.Options[1].Name)
+		assert.Equal(t, "blue", loaded.Options[1].Values[0].Value)
+
+		var values int64
+		mockDB.Model(&models.ProductOptionValue{}).Count(&values)
+		assert.Equal(t, int64(5), values)
+	})
+
+	t.Run("Keeps options in use by variants", func(t *testing.T) {
+		rec := request(router, "PUT", optionsPath, dtos.SetProductOptionsRequest{Options: []dtos.ProductOptionRequest{
+			{Name: "Size", Values: []string{"M", "L", "XL"}},
+		}})
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = request(router, "PUT", optionsPath, dtos.SetProductOptionsRequest{Options: []dtos.ProductOptionRequest{
+			{Name: "Size", Values: []string{"M", "L"}},
+			{Name: "Colour", Values: []string{"Blue", "White"}},
+		}})
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = request(router, "PUT", optionsPath, dtos.SetProductOptionsRequest{Options: []dtos.ProductOptionRequest{
+			{Name: "Size", Values: []string{"M", "m"}},
+		}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Updates a variant", func(t *testing.T) {
+		stock := 0
+		path := fmt.Sprintf("/products/%d/variants/%d", product.ID, variants[0].ID)
+		rec := request(router, "PATCH", path, dtos.PatchProductVariantRequest{SKU: "ox-m-navy", Stock: &stock})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		var variant models.ProductVariant
+		mockDB.First(&variant, variants[0].ID)
+		assert.Equal(t, "OX-M-NAVY", variant.SKU)
+		assert.Equal(t, 0, variant.Stock)
+
+		rec = request(router, "PATCH", path, dtos.PatchProductVariantRequest{SKU: variants[1].SKU})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = request(newRouter(customer), "PATCH", path, dtos.PatchProductVariantRequest{Stock: &stock})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Orders products with variants by variant", func(t *testing.T) {
+		customerRouter := newRouter(customer)
+
+		rec := request(customerRouter, "POST", "/orders", dtos.CreateOrderRequest{
+			AddressID:  address.ID,
+			OrderItems: []dtos.OrderItemRequest{{ProductID: product.ID, Quantity: 1}},
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var other models.Product
+		mockDB.Create(&models.Product{Name: "Socks", Price: 5, Stock: 10})
+		mockDB.Last(&other)
+		rec = request(customerRouter, "POST", "/orders", dtos.CreateOrderRequest{
+			AddressID:  address.ID,
+			OrderItems: []dtos.OrderItemRequest{{ProductID: other.ID, VariantID: variants[1].ID, Quantity: 1}},
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		mockDB.Model(&models.ProductVariant{}).Where("id = ?", variants[1].ID).Updates(map[string]interface{}{"price": 42, "stock": 2})
+		rec = request(customerRouter, "POST", "/orders", dtos.CreateOrderRequest{
+			AddressID: address.ID,
+			OrderItems: []dtos.OrderItemRequest{
+				{ProductID: product.ID, VariantID: variants[1].ID, Quantity: 2},
+				{ProductID: other.ID, Quantity: 1},
+			},
+		})
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+		var order models.Order
+		json.Unmarshal(rec.Body.Bytes(), &order)
+		assert.Equal(t, 89.0, order.Total)
+		assert.Equal(t, variants[1].ID, *order.OrderItems[0].VariantID)
+		assert.Equal(t, "OXFORD-SHIRT-M-WHITE", order.OrderItems[0].SKU)
+		assert.Nil(t, order.OrderItems[1].VariantID)
+
+		// Stock is taken from the variant, not from the product
+		var variant models.ProductVariant
+		mockDB.First(&variant, variants[1].ID)
+		assert.Equal(t, 0, variant.Stock)
+		mockDB.First(&product, product.ID)
+		assert.Equal(t, 1, product.Stock)
+
+		rec = request(customerRouter, "POST", "/orders", dtos.CreateOrderRequest{
+			AddressID:  address.ID,
+			OrderItems: []dtos.OrderItemRequest{{ProductID: product.ID, VariantID: variants[1].ID, Quantity: 1}},
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"Insufficient stock for variant OXFORD-SHIRT-M-WHITE of product ID: 1"}`, rec.Body.String())
+	})
+
+	t.Run("Only deletes variants that were never ordered", func(t *testing.T) {
+		rec := request(router, "DELETE", fmt.Sprintf("/products/%d/variants/%d", product.ID, variants[1].ID), nil)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = request(router, "DELETE", fmt.Sprintf("/products/%d/variants/%d", product.ID, variants[2].ID), nil)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		var count int64
+		mockDB.Table("product_variant_option_values").Where("product_variant_id = ?", variants[2].ID).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Products with variants are in stock while a variant is", func(t *testing.T) {
+		inStock := func() []string {
+			rec := request(router, "GET", "/products?in_stock=true&sort=id", nil)
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			var response struct {
+				Products []models.Product `json:"products"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &response)
+
+			names := []string{}
+			for _, product := range response.Products {
+				names = append(names, product.Name)
+			}
+			return names
+		}
+
+		// The product's own stock does not count once it has variants
+		mockDB.Model(&models.ProductVariant{}).Where("product_id = ?", product.ID).Update("stock", 0)
+		mockDB.Model(&product).Update("stock", 5)
+		assert.Equal(t, []string{"Socks"}, inStock())
+
+		mockDB.Model(&models.ProductVariant{}).Where("id = ?", variants[0].ID).Update("stock", 2)
+		mockDB.Model(&product).Update("stock", 0)
+		assert.Equal(t, []string{"Oxford Shirt", "Socks"}, inStock())
+	})
+
+	t.Run("Filters and sorts products with variants by their variants", func(t *testing.T) {
+		list := func(query string) []string {
+			rec := request(router, "GET", "/products?"+query, nil)
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+			var response struct {
+				Products []models.Product `json:"products"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &response)
+
+			names := []string{}
+			for _, product := range response.Products {
+				names = append(names, product.Name)
+			}
+			return names
+		}
+
+		// The shirt's own price and stock do not count once it has variants
+		mockDB.Model(&product).Updates(map[string]interface{}{"price": 100, "stock": 5})
+		mockDB.Model(&models.ProductVariant{}).Where("product_id = ?", product.ID).Updates(map[string]interface{}{"price": 30, "stock": 3})
+		mockDB.Model(&models.ProductVariant{}).Where("id = ?", variants[0].ID).Update("price", 60)
+
+		assert.Equal(t, []string{"Oxford Shirt"}, list("min_price=50"))
+		assert.Equal(t, []string{"Oxford Shirt", "Socks"}, list("max_price=30&sort=id"))
+		assert.Empty(t, list("min_price=70"))
+		assert.Equal(t, []string{"Socks", "Oxford Shirt"}, list("sort=price"))
+		assert.Equal(t, []string{"Oxford Shirt", "Socks"}, list("sort=-price"))
+		assert.Equal(t, []string{"Socks", "Oxford Shirt"}, list("sort=stock"))
+	})
+
+	t.Run("Generates variants from the options current once the product is locked", func(t *testing.T) {
+		scarf := models.Product{Name: "Scarf", Price: 20, Stock: 0}
+		mockDB.Create(&scarf)
+
+		rec := request(router, "PUT", fmt.Sprintf("/products/%d/options", scarf.ID), dtos.SetProductOptionsRequest{Options: []dtos.ProductOptionRequest{
+			{Name: "Colour", Values: []string{"Red", "Blue"}},
+		}})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		// Remove a value once the product is locked, as a concurrent option change committing first would
+		removed := false
+		mockDB.Callback().Query().Before("gorm:query").Register("test:remove_value", func(tx *gorm.DB) {
+			if _, locked := tx.Statement.Clauses["FOR"]; !locked || removed {
+				return
+			}
+			removed = true
+			tx.Statement.ConnPool.ExecContext(tx.Statement.Context, "DELETE FROM product_option_values WHERE value = 'Blue'")
+		})
+		defer mockDB.Callback().Query().Remove("test:remove_value")
+
+		rec = request(router, "POST", fmt.Sprintf("/products/%d/variants/generate", scarf.ID), nil)
+		assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+		assert.True(t, removed)
+
+		var created []models.ProductVariant
+		json.Unmarshal(rec.Body.Bytes(), &created)
+		if assert.Len(t, created, 1) {
+			assert.Equal(t, "SCARF-RED", created[0].SKU)
+		}
+	})
+
+	t.Run("Reports SKUs taken after they were checked", func(t *testing.T) {
+		hat := models.Product{Name: "Cap", Price: 15, Stock: 0}
+		mockDB.Create(&hat)
+
+		rec := request(router, "PUT", fmt.Sprintf("/products/%d/options", hat.ID), dtos.SetProductOptionsRequest{Options: []dtos.ProductOptionRequest{
+			{Name: "Colour", Values: []string{"Red"}},
+		}})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+		// Take the SKU in the same transaction, as a concurrent request committing first would
+		mockDB.Callback().Create().Before("gorm:create").Register("test:take_sku", func(tx *gorm.DB) {
+			if _, ok := tx.Statement.Dest.(*[]models.ProductVariant); ok {
+				tx.Statement.ConnPool.ExecContext(tx.Statement.Context,
+					"INSERT INTO product_variants (product_id, sku, price, stock, weight, created_at, updated_at) VALUES (?, 'CAP-RED', 1, 0, 0, ?, ?)",
+					product.ID, time.Now(), time.Now())
+			}
+		})
+		defer mockDB.Callback().Create().Remove("test:take_sku")
+
+		rec = request(router, "POST", fmt.Sprintf("/products/%d/variants/generate", hat.ID), nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"SKU already exists, use a different sku_prefix"}`, rec.Body.String())
+	})
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// GetProfile godoc
+// @Summary Get the current user's profile
+// @Description Retrieve the profile of the logged in user, including their assigned roles.
+// @Tags Users
+// @Produce json
+// @Success 200 {object} models.User "Successfully retrieved profile"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/me [get]
+func GetProfile(c *gin.Context) {
+	user, ok := requireUserSession(c)
+	if !ok {
+		return
+	}
+
+	if err := db.DB.Model(&user).Association("Roles").Find(&user.Roles); err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile godoc
+// @Summary Update the current user's profile
+// @Description Update the logged in user's names and email. A new email address only replaces the current one after it is verified through the link sent to it. Only the latest link sent to the user works.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body dtos.UpdateProfileRequest true "Profile fields to change"
+// @Success 200 {object} dtos.ProfileResponse "Profile updated successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error or email already in use"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/me [patch]
+func UpdateProfile(c *gin.Context) {
+	var req dtos.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.FirstName != nil {
+		user.FirstName = strings.TrimSpace(*req.FirstName)
+		updates["first_name"] = user.FirstName
+	}
+	if req.LastName != nil {
+		user.LastName = strings.TrimSpace(*req.LastName)
+		updates["last_name"] = user.LastName
+	}
+
+	if (req.FirstName != nil && user.FirstName == "") || (req.LastName != nil && user.LastName == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Names cannot be blank"})
+		return
+	}
+
+	var pendingEmail string
+	if req.Email != nil && !strings.EqualFold(strings.TrimSpace(*req.Email), user.Email) {
+		pendingEmail = strings.TrimSpace(*req.Email)
+
+		var emailTaken int64
+		db.DB.Model(&models.User{}).Where("email = ? AND id <> ?", pendingEmail, user.ID).Count(&emailTaken)
+		if emailTaken > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User with this email already exists."})
+			return
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := db.DB.Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+	}
+
+	response := dtos.ProfileResponse{Msg: "Profile updated successfully", User: user}
+
+	if pendingEmail != "" {
+		if err := sendVerificationEmail(&user, pendingEmail); err != nil {
+			log.Printf("Failed to send verification email to %v: %v", pendingEmail, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+			return
+		}
+
+		response.Msg = "Profile updated, check the new email address to confirm the change"
+		response.PendingEmail = pendingEmail
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ChangePassword godoc
+// @Summary Change the current user's password
+// @Description Change the logged in user's password after confirming the current one. Every existing session is signed out, and a new token pair for this device is returned.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body dtos.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} dtos.TokenRefreshResponse "Password changed successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error, mismatched passwords or password rejected by the password policy"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, or the current password is incorrect"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 429 {object} dtos.ErrorResponse "Too many failed attempts"
+// @Header 429 {integer} Retry-After "Seconds to wait before trying again"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/me/password [post]
+func ChangePassword(c *gin.Context) {
+	var req dtos.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	if req.Password != req.PasswordConfirm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passwords do not match"})
+		return
+	}
+
+	throttleKey := fmt.Sprintf("password:%d", user.ID)
+
+	retryAfter, err := loginRetryAfter(throttleKey)
+	if err != nil {
+		log.Printf("Failed to check password change throttle for %v: %v", user.Email, err)
+	}
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, please try again later"})
+		return
+	}
+
+	if err := user.IsValidPassword(req.CurrentPassword); err != nil {
+		recordLoginFailure(throttleKey)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	resetLoginFailures(throttleKey)
+
+	if !checkPasswordPolicy(c, req.Password, user.Email) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Failed to hash password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+
+		return revokeUserSessions(tx, user.ID)
+	})
+	if err != nil {
+		log.Printf("Failed to change password for user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	// Tokens issued within the current second survive the TokensValidAfter check,
+	// so the token used for this request is revoked explicitly.
+	if claims, ok := c.Get("token_claims"); ok {
+		if err := middleware.RevokeAccessToken(claims.(jwt.MapClaims)); err != nil {
+			log.Printf("Failed to revoke access token: %v", err)
+		}
+	}
+
+	tokens, err := issueTokens(c, &user, "")
+	if err != nil {
+		log.Printf("failed to generate JWT token for user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.TokenRefreshResponse{
+		Msg:          "Password changed successfully",
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
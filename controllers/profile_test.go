@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/mailer"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestProfile(t *testing.T) {
+	t.Setenv("LOGIN_BACKOFF_BASE", "0")
+
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.Session{},
+		&models.EmailVerificationToken{}, &models.LoginThrottle{}, &models.AccountLockout{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	sender := &recordingSender{}
+	mailer.SetSender(sender)
+	defer mailer.SetSender(mailer.LogSender{})
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	user := models.User{Email: "user@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&user)
+
+	other := models.User{Email: "taken@example.com", FirstName: "Jane", LastName: "Doe", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&other)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(middleware.LoadAuthUserMiddleware())
+	router.GET("/users/me", GetProfile)
+	router.PATCH("/users/me", UpdateProfile)
+	router.POST("/users/me/password", ChangePassword)
+	router.GET("/verify-email", VerifyEmail)
+
+	request := func(method, path, accessToken string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	session, _ := issueTokens(newTestContext(), &user, "")
+
+	t.Run("Returns the current user", func(t *testing.T) {
+		rec := request("GET", "/users/me", session.AccessToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var profile models.User
+		json.Unmarshal(rec.Body.Bytes(), &profile)
+		assert.Equal(t, user.Email, profile.Email)
+		assert.NotContains(t, rec.Body.String(), "password")
+
+		rec = request("GET", "/users/me", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Updates names", func(t *testing.T) {
+		lastName := "Smith"
+		rec := request("PATCH", "/users/me", session.AccessToken, dtos.UpdateProfileRequest{LastName: &lastName})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var updated models.User
+		mockDB.First(&updated, user.ID)
+		assert.Equal(t, "John", updated.FirstName)
+		assert.Equal(t, "Smith", updated.LastName)
+	})
+
+	t.Run("Changes email only after the new address is verified", func(t *testing.T) {
+		taken := other.Email
+		rec := request("PATCH", "/users/me", session.AccessToken, dtos.UpdateProfileRequest{Email: &taken})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		invalid := "not-an-email"
+		rec = request("PATCH", "/users/me", session.AccessToken, dtos.UpdateProfileRequest{Email: &invalid})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		newEmail := "new@example.com"
+		rec = request("PATCH", "/users/me", session.AccessToken, dtos.UpdateProfileRequest{Email: &newEmail})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.ProfileResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.Equal(t, newEmail, response.PendingEmail)
+		assert.Equal(t, user.Email, response.User.Email)
+
+		assert.Len(t, sender.messages, 1)
+		assert.Equal(t, newEmail, sender.messages[0].To)
+
+		rec = request("GET", "/verify-email?token="+tokenFromMessage(sender.messages[0]), "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var updated models.User
+		mockDB.First(&updated, user.ID)
+		assert.Equal(t, newEmail, updated.Email)
+		assert.True(t, updated.IsEmailVerified())
+	})
+
+	t.Run("Only the latest verification link changes the email", func(t *testing.T) {
+		sender.messages = nil
+
+		for _, email := range []string{"first@example.com", "second@example.com"} {
+			rec := request("PATCH", "/users/me", session.AccessToken, dtos.UpdateProfileRequest{Email: &email})
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+		assert.Len(t, sender.messages, 2)
+
+		rec := request("GET", "/verify-email?token="+tokenFromMessage(sender.messages[0]), "", nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		rec = request("GET", "/verify-email?token="+tokenFromMessage(sender.messages[1]), "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var updated models.User
+		mockDB.First(&updated, user.ID)
+		assert.Equal(t, "second@example.com", updated.Email)
+	})
+
+	t.Run("Requires the current password to change it", func(t *testing.T) {
+		otherDevice, _ := issueTokens(newTestContext(), &user, "")
+
+		rec := request("POST", "/users/me/password", session.AccessToken, dtos.ChangePasswordRequest{
+			CurrentPassword: "wrongpassword", Password: "newpassword", PasswordConfirm: "newpassword",
+		})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("POST", "/users/me/password", session.AccessToken, dtos.ChangePasswordRequest{
+			CurrentPassword: "password", Password: "newpassword", PasswordConfirm: "newpassword",
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.TokenRefreshResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+
+		var updated models.User
+		mockDB.First(&updated, user.ID)
+		assert.NoError(t, updated.IsValidPassword("newpassword"))
+
+		// Every earlier session is signed out; the returned tokens keep this device signed in
+		rec = request("GET", "/users/me", session.AccessToken, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("GET", "/users/me", otherDevice.AccessToken, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("GET", "/users/me", response.AccessToken, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("Keeps the password when sessions cannot be revoked", func(t *testing.T) {
+		current, _ := issueTokens(newTestContext(), &user, "")
+
+		mockDB.Callback().Update().Before("gorm:update").Register("test:fail_sessions", func(tx *gorm.DB) {
+			if tx.Statement.Table == "sessions" {
+				tx.AddError(errors.New("sessions unavailable"))
+			}
+		})
+		defer mockDB.Callback().Update().Remove("test:fail_sessions")
+
+		rec := request("POST", "/users/me/password", current.AccessToken, dtos.ChangePasswordRequest{
+			CurrentPassword: "newpassword", Password: "thirdpassword", PasswordConfirm: "thirdpassword",
+		})
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		var updated models.User
+		mockDB.First(&updated, user.ID)
+		assert.NoError(t, updated.IsValidPassword("newpassword"))
+	})
+}
@@ -0,0 +1,337 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description Retrieve every permission that can be granted to a role.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.Permission "Successfully retrieved permissions"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The roles:manage permission is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/permissions [get]
+func ListPermissions(c *gin.Context) {
+	var permissions []models.Permission
+	if err := db.DB.Order("name").Find(&permissions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description Retrieve every role along with the permissions it grants.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.Role "Successfully retrieved roles"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The roles:manage permission is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/roles [get]
+func ListRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := db.DB.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// CreateRole godoc
+// @Summary Create a role
+// @Description Create a new role granting the given permissions.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param input body dtos.RoleRequest true "Role information"
+// @Success 201 {object} models.Role "Role created successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid input data or unknown permission"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The roles:manage permission is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/roles [post]
+func CreateRole(c *gin.Context) {
+	var req dtos.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	permissions, err := findPermissions(req.Permissions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var existing int64
+	db.DB.Model(&models.Role{}).Where("name = ?", req.Name).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Role with this name already exists"})
+		return
+	}
+
+	role := models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+
+	if err := db.DB.Create(&role).Error; err != nil {
+		log.Printf("Failed to create role: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to create role"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole godoc
+// @Summary Update a role
+// @Description Replace the name, description and permissions of a role. Built-in roles cannot be renamed, and the admin role always grants every permission.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param input body dtos.RoleRequest true "Role information"
+// @Success 200 {object} models.Role "Role updated successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid input data or unknown permission"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The roles:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "Role not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/roles/{id} [put]
+func UpdateRole(c *gin.Context) {
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || roleID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid role ID"})
+		return
+	}
+
+	var role models.Role
+	result := db.DB.First(&role, roleID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Role not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return
+	}
+
+	var req dtos.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	if role.IsBuiltIn() && req.Name != role.Name {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Built-in roles cannot be renamed"})
+		return
+	}
+
+	if role.Name == models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "The admin role always grants every permission"})
+		return
+	}
+
+	permissions, err := findPermissions(req.Permissions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	role.Name = req.Name
+	role.Description = req.Description
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&role).Error; err != nil {
+			return err
+		}
+		return tx.Model(&role).Association("Permissions").Replace(permissions)
+	})
+	if err != nil {
+		log.Printf("Failed to update role %v: %v", role.ID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole godoc
+// @Summary Delete a role
+// @Description Delete a role and unassign it from every user. Built-in roles cannot be deleted.
+// @Tags Admin
+// @Param id path int true "Role ID"
+// @Success 204 "Role deleted successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid role ID or built-in role"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The roles:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "Role not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/roles/{id} [delete]
+func DeleteRole(c *gin.Context) {
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || roleID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid role ID"})
+		return
+	}
+
+	var role models.Role
+	result := db.DB.First(&role, roleID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Role not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return
+	}
+
+	if role.IsBuiltIn() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Built-in roles cannot be deleted"})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: fmt.Sprintf("Failed to delete role: %v", err)})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AssignUserRoles godoc
+// @Summary Assign roles to a user
+// @Description Replace the roles a user holds in addition to their base role. Built-in roles cannot be assigned: they are base roles, and admins are created through invitations.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param input body dtos.AssignRolesRequest true "Role names"
+// @Success 200 {object} models.User "Roles assigned successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID, unknown role or built-in role"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The roles:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "User not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/users/{id}/roles [put]
+func AssignUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	result := db.DB.First(&user, userID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return
+	}
+
+	var req dtos.AssignRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	var roles []models.Role
+	if len(req.Roles) > 0 {
+		if err := db.DB.Where("name IN ?", req.Roles).Find(&roles).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	if missing := missingNames(req.Roles, roles, func(role models.Role) string { return role.Name }); missing != "" {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Unknown role: %s", missing)})
+		return
+	}
+
+	// Built-in roles are base roles. Assigning one would grant its permissions without
+	// changing User.Role, which the checks on admin accounts rely on
+	for _, role := range roles {
+		if role.IsBuiltIn() {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: fmt.Sprintf("Built-in role %s cannot be assigned", role.Name)})
+			return
+		}
+	}
+
+	if err := db.DB.Model(&user).Association("Roles").Replace(roles); err != nil {
+		log.Printf("Failed to assign roles to user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to assign roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// findPermissions loads the named permissions, failing if any of them does not exist.
+func findPermissions(names []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+
+	if err := db.DB.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	if missing := missingNames(names, permissions, func(permission models.Permission) string { return permission.Name }); missing != "" {
+		return nil, fmt.Errorf("Unknown permission: %s", missing)
+	}
+
+	return permissions, nil
+}
+
+// missingNames returns the first of the requested names not present in found, or "".
+func missingNames[T any](requested []string, found []T, name func(T) string) string {
+	present := make(map[string]bool, len(found))
+	for _, item := range found {
+		present[name(item)] = true
+	}
+
+	for _, requestedName := range requested {
+		if !present[requestedName] {
+			return requestedName
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRoleManagement(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.Order{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	db.SeedRolesAndPermissions()
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: "password"}
+	mockDB.Create(&admin)
+	admin.LoadPermissions(mockDB)
+
+	clerk := models.User{Email: "clerk@example.com", FirstName: "Clerk", LastName: "User", Role: "customer", Password: "password"}
+	mockDB.Create(&clerk)
+
+	newRouter := func(user models.User) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", user)
+		})
+
+		roleAdmin := router.Group("/admin", middleware.RequirePermission(models.PermissionRolesManage))
+		roleAdmin.GET("/roles", ListRoles)
+		roleAdmin.POST("/roles", CreateRole)
+		roleAdmin.DELETE("/roles/:id", DeleteRole)
+		roleAdmin.PUT("/users/:id/roles", AssignUserRoles)
+		return router
+	}
+
+	request := func(router *gin.Engine, method, path string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Successfully grants a custom role to a user", func(t *testing.T) {
+		router := newRouter(admin)
+
+		rec := request(router, "POST", "/admin/roles", dtos.RoleRequest{
+			Name:        "warehouse_clerk",
+			Description: "Fulfils orders",
+			Permissions: []string{models.PermissionOrdersReadAll, models.PermissionOrdersUpdateStatus},
+		})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var role models.Role
+		err := json.Unmarshal(rec.Body.Bytes(), &role)
+		assert.NoError(t, err)
+		assert.Len(t, role.Permissions, 2)
+
+		rec = request(router, "PUT", fmt.Sprintf("/admin/users/%d/roles", clerk.ID), dtos.AssignRolesRequest{Roles: []string{"warehouse_clerk"}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		err = clerk.LoadPermissions(mockDB)
+		assert.NoError(t, err)
+		assert.True(t, clerk.HasPermission(models.PermissionOrdersUpdateStatus))
+		assert.False(t, clerk.HasPermission(models.PermissionProductsDelete))
+	})
+
+	t.Run("Fails with an unknown permission", func(t *testing.T) {
+		router := newRouter(admin)
+
+		rec := request(router, "POST", "/admin/roles", dtos.RoleRequest{
+			Name:        "auditor",
+			Permissions: []string{"ledger:read"},
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Unknown permission: ledger:read", response.Error)
+	})
+
+	t.Run("Fails to delete a built-in role", func(t *testing.T) {
+		router := newRouter(admin)
+
+		var customerRole models.Role
+		mockDB.Where("name = ?", models.RoleCustomer).First(&customerRole)
+
+		rec := request(router, "DELETE", fmt.Sprintf("/admin/roles/%d", customerRole.ID), nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Fails to assign a built-in role", func(t *testing.T) {
+		router := newRouter(admin)
+
+		rec := request(router, "PUT", fmt.Sprintf("/admin/users/%d/roles", clerk.ID), dtos.AssignRolesRequest{Roles: []string{"warehouse_clerk", models.RoleAdmin}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.JSONEq(t, `{"error":"Built-in role admin cannot be assigned"}`, rec.Body.String())
+
+		err := clerk.LoadPermissions(mockDB)
+		assert.NoError(t, err)
+		assert.False(t, clerk.HasPermission(models.PermissionProductsDelete))
+	})
+
+	t.Run("Fails when user lacks the permission", func(t *testing.T) {
+		customer := models.User{Email: "customer@example.com", FirstName: "Jane", LastName: "Doe", Role: "customer", Password: "password"}
+		mockDB.Create(&customer)
+		customer.LoadPermissions(mockDB)
+
+		rec := request(newRouter(customer), "GET", "/admin/roles", nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Unauthorized access, the roles:manage permission is required", response.Error)
+	})
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description Retrieve the devices the logged in user is signed in on, with the session making the request marked as current.
+// @Tags Sessions
+// @Produce json
+// @Success 200 {array} models.Session "Successfully retrieved sessions"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/sessions [get]
+func ListSessions(c *gin.Context) {
+	user, ok := requireUserSession(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := activeSessions(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve sessions"})
+		return
+	}
+
+	if claims, ok := c.Get("token_claims"); ok {
+		familyID, _ := claims.(jwt.MapClaims)["family"].(string)
+		for i := range sessions {
+			sessions[i].Current = sessions[i].FamilyID == familyID
+		}
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Terminate a session
+// @Description Signs the logged in user out of one of their sessions, revoking its access and refresh tokens.
+// @Tags Sessions
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} dtos.MessageResponse "Session terminated successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid session ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 404 {object} dtos.ErrorResponse "Session not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /users/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || sessionID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid session ID"})
+		return
+	}
+
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	terminateSession(c, db.DB.Where("user_id = ?", user.ID), sessionID)
+}
+
+// ListUserSessions godoc
+// @Summary List a user's active sessions
+// @Description Allows an admin to retrieve the devices any user is signed in on.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} models.Session "Successfully retrieved sessions"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid user ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/users/{id}/sessions [get]
+func ListUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid user ID"})
+		return
+	}
+
+	sessions, err := activeSessions(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to retrieve sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeUserSession godoc
+// @Summary Terminate any user's session
+// @Description Allows an admin to sign a user out of one of their sessions.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} dtos.MessageResponse "Session terminated successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid session ID"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "The users:manage permission is required"
+// @Failure 404 {object} dtos.ErrorResponse "Session not found"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /admin/sessions/{id} [delete]
+func RevokeUserSession(c *gin.Context) {
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || sessionID <= 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid session ID"})
+		return
+	}
+
+	terminateSession(c, db.DB, sessionID)
+}
+
+// activeSessions returns the user's sessions that have not been terminated or expired,
+// most recently used first.
+func activeSessions(userID uint) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := db.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+
+	return sessions, err
+}
+
+// terminateSession revokes the active session with the given ID found through scope,
+// writing the response.
+func terminateSession(c *gin.Context, scope *gorm.DB, sessionID int) {
+	var session models.Session
+	result := scope.Where("revoked_at IS NULL AND expires_at > ?", time.Now()).First(&session, sessionID)
+	if result.Error != nil {
+		if result.Error.Error() == "record not found" {
+			c.JSON(http.StatusNotFound, dtos.ErrorResponse{Error: "Session not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: result.Error.Error()})
+		}
+		return
+	}
+
+	if err := revokeTokenFamily(session.FamilyID); err != nil {
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to terminate session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "Session terminated successfully"})
+}
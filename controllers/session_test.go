@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSessions(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.Permission{}, &models.Role{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.Session{}, &models.LoginThrottle{}, &models.AccountLockout{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	user := models.User{Email: "user@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: string(hashedPassword)}
+	mockDB.Create(&user)
+
+	admin := models.User{Email: "admin@example.com", FirstName: "Admin", LastName: "User", Role: "admin", Password: string(hashedPassword)}
+	mockDB.Create(&admin)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(middleware.LoadAuthUserMiddleware())
+	router.POST("/login", LoginUser)
+	router.POST("/token/refresh", RefreshAccessToken)
+	router.GET("/users/sessions", ListSessions)
+	router.DELETE("/users/sessions/:id", RevokeSession)
+
+	userAdmin := router.Group("/admin", middleware.RequirePermission(models.PermissionUsersManage))
+	userAdmin.GET("/users/:id/sessions", ListUserSessions)
+	userAdmin.DELETE("/sessions/:id", RevokeUserSession)
+
+	request := func(method, path, accessToken, userAgent string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	login := func(email, userAgent string) dtos.LoginSuccessResponse {
+		rec := request("POST", "/login", "", userAgent, dtos.LoginRequest{Email: email, Password: "password"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.LoginSuccessResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return response
+	}
+
+	listSessions := func(accessToken string) []models.Session {
+		rec := request("GET", "/users/sessions", accessToken, "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var sessions []models.Session
+		json.Unmarshal(rec.Body.Bytes(), &sessions)
+		return sessions
+	}
+
+	laptop := login(user.Email, "Laptop")
+	phone := login(user.Email, "Phone")
+
+	t.Run("Lists a session per login", func(t *testing.T) {
+		sessions := listSessions(laptop.AccessToken)
+		assert.Len(t, sessions, 2)
+
+		userAgents := map[string]bool{}
+		for _, session := range sessions {
+			userAgents[session.UserAgent] = session.Current
+		}
+		assert.Equal(t, map[string]bool{"Laptop": true, "Phone": false}, userAgents)
+	})
+
+	t.Run("Keeps a session across token refreshes", func(t *testing.T) {
+		rec := request("POST", "/token/refresh", "", "Phone v2", dtos.RefreshTokenRequest{RefreshToken: phone.RefreshToken})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var refreshed dtos.TokenRefreshResponse
+		json.Unmarshal(rec.Body.Bytes(), &refreshed)
+		phone.AccessToken, phone.RefreshToken = refreshed.AccessToken, refreshed.RefreshToken
+
+		sessions := listSessions(phone.AccessToken)
+		assert.Len(t, sessions, 2)
+		assert.Equal(t, "Phone v2", sessions[0].UserAgent)
+		assert.True(t, sessions[0].Current)
+	})
+
+	t.Run("Terminates another device's session", func(t *testing.T) {
+		sessions := listSessions(laptop.AccessToken)
+
+		var phoneSession models.Session
+		for _, session := range sessions {
+			if !session.Current {
+				phoneSession = session
+			}
+		}
+
+		rec := request("DELETE", fmt.Sprintf("/users/sessions/%d", phoneSession.ID), laptop.AccessToken, "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = request("GET", "/users/sessions", phone.AccessToken, "", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("POST", "/token/refresh", "", "", dtos.RefreshTokenRequest{RefreshToken: phone.RefreshToken})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		assert.Len(t, listSessions(laptop.AccessToken), 1)
+	})
+
+	t.Run("Cannot terminate another user's session", func(t *testing.T) {
+		other := login(admin.Email, "Admin laptop")
+		sessions := listSessions(other.AccessToken)
+
+		rec := request("DELETE", fmt.Sprintf("/users/sessions/%d", sessions[0].ID), laptop.AccessToken, "", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("Admins can terminate any user's session", func(t *testing.T) {
+		adminSession := login(admin.Email, "Admin desktop")
+
+		rec := request("GET", fmt.Sprintf("/admin/users/%d/sessions", user.ID), adminSession.AccessToken, "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var sessions []models.Session
+		json.Unmarshal(rec.Body.Bytes(), &sessions)
+		assert.Len(t, sessions, 1)
+
+		rec = request("DELETE", fmt.Sprintf("/admin/sessions/%d", sessions[0].ID), adminSession.AccessToken, "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = request("GET", "/users/sessions", laptop.AccessToken, "", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = request("GET", fmt.Sprintf("/admin/users/%d/sessions", user.ID), laptop.AccessToken, "", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RefreshAccessToken godoc
+// @Summary Exchange a refresh token for a new token pair
+// @Description Issues a new access and refresh token. Refresh tokens are single-use: the submitted token is rotated out, and replaying an already-used token revokes every token issued from the same login.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param input body dtos.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} dtos.TokenRefreshResponse "Token refreshed successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error"
+// @Failure 401 {object} dtos.ErrorResponse "Invalid or expired refresh token"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /token/refresh [post]
+func RefreshAccessToken(c *gin.Context) {
+	var req dtos.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleValidationErrors(err, c)
+		return
+	}
+
+	claims, err := models.ParseJwtToken(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Invalid or expired refresh token"})
+		return
+	}
+
+	jti, _ := claims["jti"].(string)
+	if tokenType, _ := claims["type"].(string); tokenType != models.TokenTypeRefresh || jti == "" {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Invalid or expired refresh token"})
+		return
+	}
+
+	var stored models.RefreshToken
+	if err := db.DB.Where("jti = ?", jti).First(&stored).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Invalid or expired refresh token"})
+		return
+	}
+
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		log.Printf("Refresh token reuse detected, revoking token family %v", stored.FamilyID)
+		if err := revokeTokenFamily(stored.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to refresh token"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Refresh token has already been used, please login again"})
+		return
+	}
+
+	// Mark the token as used only if no concurrent request got to it first
+	result := db.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", stored.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		log.Printf("Failed to mark refresh token %v as used: %v", stored.ID, result.Error)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		log.Printf("Refresh token reuse detected, revoking token family %v", stored.FamilyID)
+		if err := revokeTokenFamily(stored.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to refresh token"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Refresh token has already been used, please login again"})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, stored.UserID).Error; err != nil || user.IsSuspended() {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Invalid or expired refresh token"})
+		return
+	}
+
+	tokens, err := issueTokens(c, &user, stored.FamilyID)
+	if err != nil {
+		log.Printf("failed to generate JWT token for user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.TokenRefreshResponse{
+		Msg:          "Token refreshed successfully",
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// issueTokens generates a token pair for the user and persists its refresh token.
+// An empty familyID starts a new token family, as happens on every fresh login, and
+// records a session for the client making the request; rotating a token within a
+// family refreshes that session instead.
+func issueTokens(c *gin.Context, user *models.User, familyID string) (*models.TokenPair, error) {
+	tokens, err := models.GenerateJwtTokens(user, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&tokens.Refresh).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		if familyID == "" {
+			return tx.Create(&models.Session{
+				FamilyID:   tokens.Refresh.FamilyID,
+				UserID:     user.ID,
+				UserAgent:  c.Request.UserAgent(),
+				IPAddress:  c.ClientIP(),
+				LastSeenAt: now,
+				ExpiresAt:  tokens.Refresh.ExpiresAt,
+			}).Error
+		}
+
+		return tx.Model(&models.Session{}).Where("family_id = ?", familyID).Updates(map[string]interface{}{
+			"user_agent":   c.Request.UserAgent(),
+			"ip_address":   c.ClientIP(),
+			"last_seen_at": now,
+			"expires_at":   tokens.Refresh.ExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// revokeTokenFamily ends the session started by a login, revoking every outstanding
+// refresh token descended from it. The session's access tokens are rejected by
+// LoadAuthUserMiddleware from then on.
+func revokeTokenFamily(familyID string) error {
+	now := time.Now()
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		log.Printf("Failed to revoke token family %v: %v", familyID, err)
+	}
+
+	return err
+}
+
+// revokeUserSessions invalidates every access and refresh token issued to the user so far.
+// Pass the transaction of a change that must not apply unless the sessions are revoked.
+func revokeUserSessions(conn *gorm.DB, userID uint) error {
+	now := time.Now()
+
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("tokens_valid_after", now).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRefreshAccessToken(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Email: "user@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: "password"}
+	mockDB.Create(&user)
+
+	refresh := func(refreshToken string) *httptest.ResponseRecorder {
+		gin.SetMode(gin.TestMode)
+
+		router := gin.Default()
+		router.POST("/token/refresh", RefreshAccessToken)
+
+		body, _ := json.Marshal(dtos.RefreshTokenRequest{RefreshToken: refreshToken})
+		req, _ := http.NewRequest("POST", "/token/refresh", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Successfully rotates refresh token", func(t *testing.T) {
+		tokens, err := issueTokens(newTestContext(), &user, "")
+		assert.NoError(t, err)
+
+		rec := refresh(tokens.RefreshToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response dtos.TokenRefreshResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEmpty(t, response.RefreshToken)
+		assert.NotEqual(t, tokens.RefreshToken, response.RefreshToken)
+
+		var used models.RefreshToken
+		mockDB.Where("jti = ?", tokens.Refresh.JTI).First(&used)
+		assert.NotNil(t, used.UsedAt)
+	})
+
+	t.Run("Revokes token family when a used token is replayed", func(t *testing.T) {
+		tokens, _ := issueTokens(newTestContext(), &user, "")
+
+		rec := refresh(tokens.RefreshToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var rotated dtos.TokenRefreshResponse
+		json.Unmarshal(rec.Body.Bytes(), &rotated)
+
+		rec = refresh(tokens.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Refresh token has already been used, please login again", response.Error)
+
+		// The token issued by the legitimate rotation is now revoked too
+		rec = refresh(rotated.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Fails with an access token", func(t *testing.T) {
+		tokens, _ := issueTokens(newTestContext(), &user, "")
+
+		rec := refresh(tokens.AccessToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Fails with a malformed token", func(t *testing.T) {
+		rec := refresh("not-a-token")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Invalid or expired refresh token", response.Error)
+	})
+
+	t.Run("Fails when the replayed token family cannot be revoked", func(t *testing.T) {
+		tokens, _ := issueTokens(newTestContext(), &user, "")
+
+		rec := refresh(tokens.RefreshToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var rotated dtos.TokenRefreshResponse
+		json.Unmarshal(rec.Body.Bytes(), &rotated)
+
+		mockDB.Migrator().DropTable(&models.Session{})
+		defer mockDB.AutoMigrate(&models.Session{})
+
+		rec = refresh(tokens.RefreshToken)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		var revoked int64
+		mockDB.Model(&models.RefreshToken{}).Where("family_id = ? AND revoked_at IS NOT NULL", tokens.Refresh.FamilyID).Count(&revoked)
+		assert.Zero(t, revoked)
+	})
+}
@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -9,14 +12,19 @@ import (
 
 	"github.com/cgzirim/ecommerce-api/db"
 	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
 	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
-const ADMIN_SECRET_KEY = "admin123"
 const LOGIN_ON_REGISTRATION = true
 
+var errInvitationUnavailable = errors.New("invitation is no longer available")
+
 // RegisterCustomer godoc
 // @Summary Register a new customer
 // @Description Allows a user to register as a customer by providing necessary details.
@@ -27,7 +35,7 @@ const LOGIN_ON_REGISTRATION = true
 // @Param input body dtos.CustomerRegistrationRequest true "Customer registration details"
 //
 // @Success 200 {object} dtos.RegistrationSuccessResponse "Successfully registered customer"
-// @Failure 400 {object} dtos.ErrorResponse "Validation error or mismatched passwords"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error, mismatched passwords or password rejected by the password policy"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Router /register [post]
 func RegisterCustomer(c *gin.Context) {
@@ -43,6 +51,10 @@ func RegisterCustomer(c *gin.Context) {
 		return
 	}
 
+	if !checkPasswordPolicy(c, req.Password, req.Email) {
+		return
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Printf("Failed to hash password: %v", err)
@@ -68,12 +80,18 @@ func RegisterCustomer(c *gin.Context) {
 		return
 	}
 
+	if err := sendVerificationEmail(&user, user.Email); err != nil {
+		log.Printf("Failed to send verification email to %v: %v", user.Email, err)
+	}
+
 	var accessToken, refreshToken string
 
 	if LOGIN_ON_REGISTRATION {
-		accessToken, refreshToken, err = models.GenerateJwtTokens(&user)
+		tokens, err := issueTokens(c, &user, "")
 		if err != nil {
 			log.Printf("failed to generate JWT token for user %v: %v", user.Email, err)
+		} else {
+			accessToken, refreshToken = tokens.AccessToken, tokens.RefreshToken
 		}
 	}
 
@@ -89,7 +107,7 @@ func RegisterCustomer(c *gin.Context) {
 
 // RegisterAdmin godoc
 // @Summary Register a new admin
-// @Description Allows a user to register as an admin by providing the necessary details, including a secret key for authentication (```secret_key: admin123```).
+// @Description Allows a user to register as an admin by providing the necessary details, including a single-use invitation token issued by an existing admin.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -97,7 +115,8 @@ func RegisterCustomer(c *gin.Context) {
 // @Param input body dtos.AdminRegistrationRequest true "Admin registration details"
 //
 // @Success 200 {object} dtos.RegistrationSuccessResponse "Account registered successfully"
-// @Failure 400 {object} dtos.ErrorResponse "Validation error or mismatched passwords"
+// @Failure 400 {object} dtos.ErrorResponse "Validation error, mismatched passwords or password rejected by the password policy"
+// @Failure 403 {object} dtos.ErrorResponse "Invalid or expired invitation token"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Router /register/admin [post]
 func RegisterAdmin(c *gin.Context) {
@@ -112,8 +131,18 @@ func RegisterAdmin(c *gin.Context) {
 		return
 	}
 
-	if req.SecretKey != ADMIN_SECRET_KEY {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid secret key for admin registration"})
+	if !checkPasswordPolicy(c, req.Password, req.Email) {
+		return
+	}
+
+	var invitation models.AdminInvitation
+	if err := db.DB.Where("token_hash = ?", utils.HashToken(req.InvitationToken)).First(&invitation).Error; err != nil || !invitation.IsPending() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired invitation token"})
+		return
+	}
+
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, req.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This invitation was issued for a different email address"})
 		return
 	}
 
@@ -132,7 +161,36 @@ func RegisterAdmin(c *gin.Context) {
 		Role:      models.RoleAdmin,
 	}
 
-	if err := db.DB.Create(&user).Error; err != nil {
+	// An invitation addressed to this email already proves the user controls it
+	if invitation.Email != "" {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		// Consume the invitation only if a concurrent registration has not done so already
+		result := tx.Model(&models.AdminInvitation{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"used_at": time.Now(), "used_by_id": user.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvitationUnavailable
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errInvitationUnavailable) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired invitation token"})
+			return
+		}
+
 		if strings.Contains(err.Error(), "duplicate key value violates unique constraint \"uni_users_email\"") {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "User with this email already exists."})
 			return
@@ -142,12 +200,25 @@ func RegisterAdmin(c *gin.Context) {
 		return
 	}
 
+	if !user.IsEmailVerified() {
+		if err := sendVerificationEmail(&user, user.Email); err != nil {
+			log.Printf("Failed to send verification email to %v: %v", user.Email, err)
+		}
+	}
+
 	var accessToken, refreshToken string
 
-	if LOGIN_ON_REGISTRATION {
-		accessToken, refreshToken, err = models.GenerateJwtTokens(&user)
+	// Admins who must use MFA log in once they have enrolled, so no tokens are issued here
+	mfaRequired, err := mfaRequiredFor(&user)
+	if err != nil {
+		log.Printf("Failed to load permissions of user %v: %v", user.Email, err)
+	}
+	if LOGIN_ON_REGISTRATION && err == nil && !mfaRequired {
+		tokens, err := issueTokens(c, &user, "")
 		if err != nil {
 			log.Printf("failed to generate JWT token for user %v: %v", user.Email, err)
+		} else {
+			accessToken, refreshToken = tokens.AccessToken, tokens.RefreshToken
 		}
 	}
 
@@ -163,14 +234,18 @@ func RegisterAdmin(c *gin.Context) {
 
 // LoginUser godoc
 // @Summary User login
-// @Description Allows a user to login by providing email and password.
+// @Description Allows a user to login by providing email and password. Users with MFA enabled, and admins when MFA is mandatory for them, receive an MFA challenge token to complete through /login/mfa instead.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param input body dtos.LoginRequest true "Login details"
 // @Success 200 {object} dtos.LoginSuccessResponse "Login successful"
+// @Success 202 {object} dtos.MFAChallengeResponse "Second factor required"
 // @Failure 400 {object} dtos.ErrorResponse "Validation error"
 // @Failure 401 {object} dtos.ErrorResponse "Invalid credentials"
+// @Failure 403 {object} dtos.ErrorResponse "Account suspended"
+// @Failure 429 {object} dtos.ErrorResponse "Too many failed login attempts"
+// @Header 429 {integer} Retry-After "Seconds to wait before trying again"
 // @Failure 500 {object} dtos.ErrorResponse "Internal server error"
 // @Router /login [post]
 func LoginUser(c *gin.Context) {
@@ -180,27 +255,91 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
+	throttleKeys := []string{emailThrottleKey(req.Email), ipThrottleKey(c.ClientIP())}
+
+	retryAfter, err := loginRetryAfter(throttleKeys...)
+	if err != nil {
+		log.Printf("Failed to check login throttle for %v: %v", req.Email, err)
+	}
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, please try again later"})
+		return
+	}
+
 	var user models.User
 	if err := db.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		recordLoginFailure(throttleKeys...)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
 	if err := user.IsValidPassword(req.Password); err != nil {
+		recordLoginFailure(throttleKeys...)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	accessToken, refreshToken, err := models.GenerateJwtTokens(&user)
+	resetLoginFailures(emailThrottleKey(req.Email))
+
+	continueLogin(c, user)
+}
+
+// continueLogin finishes the login of a user whose identity has been established,
+// asking for a second factor when one is needed.
+func continueLogin(c *gin.Context, user models.User) {
+	if user.IsSuspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account has been suspended"})
+		return
+	}
+
+	mfaRequired, err := mfaRequiredFor(&user)
+	if err != nil {
+		log.Printf("Failed to load permissions of user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
+	}
+
+	enrollMFA := !user.IsMFAEnabled() && mfaRequired
+	if user.IsMFAEnabled() || enrollMFA {
+		mfaToken, err := models.GenerateMFAChallengeToken(&user, enrollMFA)
+		if err != nil {
+			log.Printf("failed to generate MFA challenge token for user %v: %v", user.Email, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+			return
+		}
+
+		msg := "MFA code required"
+		if enrollMFA {
+			msg = "MFA enrollment required"
+		}
+
+		c.JSON(http.StatusAccepted, dtos.MFAChallengeResponse{
+			Msg:                   msg,
+			MFARequired:           true,
+			MFAEnrollmentRequired: enrollMFA,
+			MFAToken:              mfaToken,
+		})
+		return
+	}
+
+	completeLogin(c, user)
+}
+
+// completeLogin issues a new token pair for a user who has passed every login step.
+func completeLogin(c *gin.Context, user models.User) {
+	tokens, err := issueTokens(c, &user, "")
 	if err != nil {
 		log.Printf("failed to generate JWT token for user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		return
 	}
 
 	response := dtos.LoginSuccessResponse{
 		Msg:          "Login successful",
 		User:         user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}
 
 	// Update LastLogin to current time
@@ -211,3 +350,77 @@ func LoginUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// Logout godoc
+// @Summary Log out of the current session
+// @Description Revokes the access token used for this request along with the refresh tokens issued by the same login.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dtos.MessageResponse "Logged out successfully"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /logout [post]
+func Logout(c *gin.Context) {
+	if _, exists := c.Get("user"); !exists {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+		return
+	}
+
+	claims, exists := c.Get("token_claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+		return
+	}
+
+	tokenClaims := claims.(jwt.MapClaims)
+
+	if err := middleware.RevokeAccessToken(tokenClaims); err != nil {
+		log.Printf("Failed to revoke access token: %v", err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to log out"})
+		return
+	}
+
+	if familyID, _ := tokenClaims["family"].(string); familyID != "" {
+		if err := revokeTokenFamily(familyID); err != nil {
+			c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to log out"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "Logged out successfully"})
+}
+
+// LogoutAllSessions godoc
+// @Summary Log out of all sessions
+// @Description Revokes every access and refresh token issued to the logged in user, on all devices.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} dtos.MessageResponse "Logged out of all sessions"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key or while impersonating"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /logout/all [post]
+func LogoutAllSessions(c *gin.Context) {
+	user, ok := requireLoggedInUser(c)
+	if !ok {
+		return
+	}
+
+	if err := revokeUserSessions(db.DB, user.ID); err != nil {
+		log.Printf("Failed to revoke sessions for user %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to log out"})
+		return
+	}
+
+	// Tokens issued within the current second survive the TokensValidAfter check,
+	// so the token used for this request is revoked explicitly.
+	if claims, ok := c.Get("token_claims"); ok {
+		if err := middleware.RevokeAccessToken(claims.(jwt.MapClaims)); err != nil {
+			log.Printf("Failed to revoke access token: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "Logged out of all sessions"})
+}
@@ -6,9 +6,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/cgzirim/ecommerce-api/db"
 	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
 	"github.com/cgzirim/ecommerce-api/models"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -19,7 +21,7 @@ import (
 
 func TestRegisterCustomer(t *testing.T) {
 	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	mockDB.AutoMigrate(&models.User{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.EmailVerificationToken{})
 
 	originalDB := db.DB
 	db.SetMockDB(mockDB)
@@ -105,76 +107,115 @@ func TestRegisterCustomer(t *testing.T) {
 
 func TestRegisterAdmin(t *testing.T) {
 	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	mockDB.AutoMigrate(&models.User{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.AdminInvitation{}, &models.EmailVerificationToken{})
 
 	originalDB := db.DB
 	db.SetMockDB(mockDB)
 	defer func() { db.DB = originalDB }()
 
-	t.Run("Successfully registers admin", func(t *testing.T) {
+	register := func(adminRequest dtos.AdminRegistrationRequest) *httptest.ResponseRecorder {
 		gin.SetMode(gin.TestMode)
 
 		router := gin.Default()
 		router.POST("/register/admin", RegisterAdmin)
 
+		body, _ := json.Marshal(adminRequest)
+		req, _ := http.NewRequest("POST", "/register/admin", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("Successfully registers admin", func(t *testing.T) {
+		_, token, err := NewAdminInvitation(nil, "", ADMIN_INVITATION_TTL)
+		assert.NoError(t, err)
+
 		adminRequest := dtos.AdminRegistrationRequest{
 			Email:           "admin@example.com",
 			FirstName:       "Admin",
 			LastName:        "User",
 			Password:        "password",
 			PasswordConfirm: "password",
-			SecretKey:       ADMIN_SECRET_KEY,
+			InvitationToken: token,
 		}
-		body, _ := json.Marshal(adminRequest)
-		req, _ := http.NewRequest("POST", "/register/admin", bytes.NewBuffer(body))
-		req.Header.Set("Content-Type", "application/json")
-		rec := httptest.NewRecorder()
-		router.ServeHTTP(rec, req)
+		rec := register(adminRequest)
 
 		assert.Equal(t, http.StatusCreated, rec.Code)
 
 		var response dtos.RegistrationSuccessResponse
-		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		err = json.Unmarshal(rec.Body.Bytes(), &response)
 		assert.NoError(t, err)
 
 		assert.Equal(t, adminRequest.Email, response.User.Email)
 		assert.Equal(t, adminRequest.FirstName, response.User.FirstName)
 		assert.Equal(t, adminRequest.LastName, response.User.LastName)
 		assert.Equal(t, models.RoleAdmin, response.User.Role)
+
+		// Invitations are single-use
+		adminRequest.Email = "another.admin@example.com"
+		rec = register(adminRequest)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
 	})
 
-	t.Run("Fails with incorrect secret key", func(t *testing.T) {
-		gin.SetMode(gin.TestMode)
+	t.Run("Fails with incorrect invitation token", func(t *testing.T) {
+		rec := register(dtos.AdminRegistrationRequest{
+			Email:           "admin2@example.com",
+			FirstName:       "Admin",
+			LastName:        "User",
+			Password:        "password",
+			PasswordConfirm: "password",
+			InvitationToken: "wrongtoken#$%",
+		})
 
-		router := gin.Default()
-		router.POST("/register/admin", RegisterAdmin)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
 
-		adminRequest := dtos.AdminRegistrationRequest{
-			Email:           "admin@example.com",
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Invalid or expired invitation token", response.Error)
+	})
+
+	t.Run("Fails with expired invitation token", func(t *testing.T) {
+		_, token, _ := NewAdminInvitation(nil, "", -time.Minute)
+
+		rec := register(dtos.AdminRegistrationRequest{
+			Email:           "admin3@example.com",
 			FirstName:       "Admin",
 			LastName:        "User",
 			Password:        "password",
 			PasswordConfirm: "password",
-			SecretKey:       "wrongsecret#$%",
-		}
-		body, _ := json.Marshal(adminRequest)
-		req, _ := http.NewRequest("POST", "/register/admin", bytes.NewBuffer(body))
-		req.Header.Set("Content-Type", "application/json")
-		rec := httptest.NewRecorder()
-		router.ServeHTTP(rec, req)
+			InvitationToken: token,
+		})
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Fails when invitation is for a different email", func(t *testing.T) {
+		_, token, _ := NewAdminInvitation(nil, "invited@example.com", ADMIN_INVITATION_TTL)
+
+		rec := register(dtos.AdminRegistrationRequest{
+			Email:           "someone.else@example.com",
+			FirstName:       "Admin",
+			LastName:        "User",
+			Password:        "password",
+			PasswordConfirm: "password",
+			InvitationToken: token,
+		})
 
 		assert.Equal(t, http.StatusForbidden, rec.Code)
 
 		var response dtos.ErrorResponse
 		err := json.Unmarshal(rec.Body.Bytes(), &response)
 		assert.NoError(t, err)
-		assert.Equal(t, "Invalid secret key for admin registration", response.Error)
+		assert.Equal(t, "This invitation was issued for a different email address", response.Error)
 	})
 }
 
 func TestLogin(t *testing.T) {
 	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	mockDB.AutoMigrate(&models.User{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.LoginThrottle{}, &models.AccountLockout{})
 
 	originalDB := db.DB
 	db.SetMockDB(mockDB)
@@ -237,3 +278,76 @@ func TestLogin(t *testing.T) {
 		assert.Equal(t, "Invalid credentials", response.Error)
 	})
 }
+
+func TestLogout(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.RevokedToken{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Email: "user@example.com", FirstName: "John", LastName: "Doe", Role: "customer", Password: "password"}
+	mockDB.Create(&user)
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.Use(middleware.LoadAuthUserMiddleware())
+	router.POST("/logout", Logout)
+	router.POST("/logout/all", LogoutAllSessions)
+	router.GET("/users/addresses", ListAddresses)
+
+	request := func(method, path, accessToken string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Successfully logs out the current session", func(t *testing.T) {
+		tokens, _ := issueTokens(newTestContext(), &user, "")
+		otherTokens, _ := issueTokens(newTestContext(), &user, "")
+
+		rec := request("POST", "/logout", tokens.AccessToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = request("POST", "/logout", tokens.AccessToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		var refresh models.RefreshToken
+		mockDB.Where("jti = ?", tokens.Refresh.JTI).First(&refresh)
+		assert.NotNil(t, refresh.RevokedAt)
+
+		// Other sessions are unaffected
+		var otherRefresh models.RefreshToken
+		mockDB.Where("jti = ?", otherTokens.Refresh.JTI).First(&otherRefresh)
+		assert.Nil(t, otherRefresh.RevokedAt)
+	})
+
+	t.Run("Successfully logs out all sessions", func(t *testing.T) {
+		tokens, _ := issueTokens(newTestContext(), &user, "")
+		otherTokens, _ := issueTokens(newTestContext(), &user, "")
+
+		rec := request("POST", "/logout/all", tokens.AccessToken)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = request("GET", "/users/addresses", tokens.AccessToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		var refresh models.RefreshToken
+		mockDB.Where("jti = ?", otherTokens.Refresh.JTI).First(&refresh)
+		assert.NotNil(t, refresh.RevokedAt)
+	})
+
+	t.Run("Fails when user is unauthenticated", func(t *testing.T) {
+		rec := request("POST", "/logout", "invalid-token")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Unauthenticated, login is required", response.Error)
+	})
+}
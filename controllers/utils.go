@@ -8,6 +8,10 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/passwordpolicy"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
@@ -27,10 +31,16 @@ func handleValidationErrors(err error, c *gin.Context) {
 			switch validationErr.Tag() {
 			case "gt":
 				errorMessages[field] = fmt.Sprintf("Value must be greater than %s.", validationErr.Param())
+			case "gte":
+				errorMessages[field] = fmt.Sprintf("Value must be greater than or equal to %s.", validationErr.Param())
 			case "required":
 				errorMessages[field] = "This field is required."
 			case "min":
 				errorMessages[field] = fmt.Sprintf("Value length must be greater than or equal to %s", validationErr.Param())
+			case "max":
+				errorMessages[field] = fmt.Sprintf("Value length must be less than or equal to %s", validationErr.Param())
+			case "email":
+				errorMessages[field] = "Value must be a valid email address."
 			default:
 				errorMessages[field] = validationErr.Error()
 			}
@@ -43,3 +53,60 @@ func handleValidationErrors(err error, c *gin.Context) {
 	log.Printf("Non-validation error occurred: %v", err)
 	c.JSON(http.StatusInternalServerError, gin.H{"error": "An error occurred. Please try again."})
 }
+
+// requireUserSession returns the user for endpoints that act on the user's own account,
+// such as orders, addresses and sessions, writing an error response otherwise. API key
+// scopes only narrow permissions, and these endpoints check none, so they are not
+// reachable with an API key at all. Impersonating admins can reach them.
+func requireUserSession(c *gin.Context) (models.User, bool) {
+	authUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+		return models.User{}, false
+	}
+
+	if middleware.IsAPIKeyRequest(c) {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "This action cannot be performed with an API key"})
+		return models.User{}, false
+	}
+
+	return authUser.(models.User), true
+}
+
+// requireLoggedInUser returns the user for endpoints that must not be reachable with
+// an API key or by an impersonating admin, such as managing keys or credentials,
+// writing an error response otherwise. This keeps a leaked key from being used to mint
+// broader keys or take over the account, and keeps support staff from changing a
+// customer's credentials.
+func requireLoggedInUser(c *gin.Context) (models.User, bool) {
+	user, ok := requireUserSession(c)
+	if !ok {
+		return user, false
+	}
+
+	if middleware.IsImpersonating(c) {
+		c.JSON(http.StatusForbidden, dtos.ErrorResponse{Error: "This action cannot be performed while impersonating a user"})
+		return models.User{}, false
+	}
+
+	return user, true
+}
+
+// checkPasswordPolicy returns whether password satisfies the password policy for the
+// account with the given email, writing an error response if it does not.
+func checkPasswordPolicy(c *gin.Context, password, email string) bool {
+	err := passwordpolicy.Check(password, email)
+	if err == nil {
+		return true
+	}
+
+	var violation passwordpolicy.Violation
+	if errors.As(err, &violation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": map[string]string{"password": violation.Error()}})
+		return false
+	}
+
+	log.Printf("Failed to check password against the password policy: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password"})
+	return false
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/mailer"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const EMAIL_VERIFICATION_TTL = time.Hour * 48
+
+var errVerificationTokenUnavailable = errors.New("verification token is no longer available")
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Confirms that the user controls an email address using the token from the verification email.
+// @Tags Auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} dtos.MessageResponse "Email verified successfully"
+// @Failure 400 {object} dtos.ErrorResponse "Invalid or expired verification token"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Router /verify-email [get]
+func VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid or expired verification token"})
+		return
+	}
+
+	var verification models.EmailVerificationToken
+	err := db.DB.Where("token_hash = ?", utils.HashToken(token)).First(&verification).Error
+	if err != nil || verification.UsedAt != nil || verification.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid or expired verification token"})
+		return
+	}
+
+	var emailTaken int64
+	db.DB.Model(&models.User{}).Where("email = ? AND id <> ?", verification.Email, verification.UserID).Count(&emailTaken)
+	if emailTaken > 0 {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "User with this email already exists."})
+		return
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&models.EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL", verification.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errVerificationTokenUnavailable
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", verification.UserID).
+			Updates(map[string]interface{}{"email": verification.Email, "email_verified_at": now}).Error
+	})
+	if err != nil {
+		if errors.Is(err, errVerificationTokenUnavailable) {
+			c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Invalid or expired verification token"})
+			return
+		}
+
+		log.Printf("Failed to verify email for user %v: %v", verification.UserID, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dtos.MessageResponse{Msg: "Email verified successfully"})
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend the verification email
+// @Description Sends a new verification email to the logged in user's email address. Links sent earlier stop working.
+// @Tags Auth
+// @Produce json
+// @Success 202 {object} dtos.MessageResponse "Verification email sent"
+// @Failure 400 {object} dtos.ErrorResponse "Email is already verified"
+// @Failure 401 {object} dtos.ErrorResponse "Unauthenticated, login is required"
+// @Failure 403 {object} dtos.ErrorResponse "Request made with an API key"
+// @Failure 500 {object} dtos.ErrorResponse "Internal server error"
+// @Security BearerAuth
+// @Router /verify-email/resend [post]
+func ResendVerificationEmail(c *gin.Context) {
+	user, ok := requireUserSession(c)
+	if !ok {
+		return
+	}
+
+	if user.IsEmailVerified() {
+		c.JSON(http.StatusBadRequest, dtos.ErrorResponse{Error: "Email is already verified"})
+		return
+	}
+
+	if err := sendVerificationEmail(&user, user.Email); err != nil {
+		log.Printf("Failed to send verification email to %v: %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, dtos.ErrorResponse{Error: "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dtos.MessageResponse{Msg: "Verification email sent"})
+}
+
+// sendVerificationEmail issues a verification token for email and mails it there.
+// The email may differ from user.Email when the user is changing address. Earlier
+// tokens of the user stop working, so that an old link cannot undo a later change.
+func sendVerificationEmail(user *models.User, email string) error {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	verification := models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(EMAIL_VERIFICATION_TTL),
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&verification).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s, please confirm your email address using the link below. It expires in %v.\n\n%s?token=%s",
+			user.FirstName, EMAIL_VERIFICATION_TTL, utils.GetEnv("EMAIL_VERIFICATION_URL", "http://localhost:8080/v1/verify-email"), token,
+		),
+	})
+}
+
+// requireVerifiedEmailForOrders reports whether only users with a verified email may place orders.
+func requireVerifiedEmailForOrders() bool {
+	return utils.GetEnv("REQUIRE_VERIFIED_EMAIL_FOR_ORDERS", "false") == "true"
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/mailer"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	mockDB, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	mockDB.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Session{}, &models.EmailVerificationToken{})
+
+	originalDB := db.DB
+	db.SetMockDB(mockDB)
+	defer func() { db.DB = originalDB }()
+
+	mailDir := t.TempDir()
+	mailer.SetSender(mailer.FileSender{Dir: mailDir})
+	defer mailer.SetSender(mailer.LogSender{})
+
+	gin.SetMode(gin.TestMode)
+
+	router := gin.Default()
+	router.POST("/register", RegisterCustomer)
+	router.GET("/verify-email", VerifyEmail)
+
+	t.Run("Successfully verifies email after registration", func(t *testing.T) {
+		body, _ := json.Marshal(dtos.CustomerRegistrationRequest{
+			Email:           "customer@example.com",
+			FirstName:       "John",
+			LastName:        "Doe",
+			Password:        "password",
+			PasswordConfirm: "password",
+		})
+		req, _ := http.NewRequest("POST", "/register", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var registered dtos.RegistrationSuccessResponse
+		json.Unmarshal(rec.Body.Bytes(), &registered)
+		assert.Nil(t, registered.User.EmailVerifiedAt)
+
+		files, _ := filepath.Glob(filepath.Join(mailDir, "*.eml"))
+		assert.Len(t, files, 1)
+
+		content, err := os.ReadFile(files[0])
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "To: customer@example.com")
+
+		token := strings.TrimSpace(string(content)[strings.LastIndex(string(content), "token=")+len("token="):])
+
+		req, _ = http.NewRequest("GET", "/verify-email?token="+token, nil)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var user models.User
+		mockDB.First(&user, registered.User.ID)
+		assert.True(t, user.IsEmailVerified())
+
+		// Verification tokens are single-use
+		req, _ = http.NewRequest("GET", "/verify-email?token="+token, nil)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Fails with an invalid token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/verify-email?token=bogus", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+		var response dtos.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Invalid or expired verification token", response.Error)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/cgzirim/ecommerce-api/models"
 	"gorm.io/driver/postgres"
@@ -37,17 +38,133 @@ func MigrateDBSchemas() {
 		log.Fatal("Database connection is not initialized. Call OpenDbConnection first.")
 	}
 
-	err := DB.AutoMigrate(
+	// Orders used to be deleted along with their user. Drop that constraint so AutoMigrate
+	// recreates it with ON DELETE RESTRICT, keeping order history for accounting.
+	err := DB.Exec(`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.referential_constraints
+			WHERE constraint_name = 'fk_orders_user' AND delete_rule = 'CASCADE') THEN
+			ALTER TABLE orders DROP CONSTRAINT fk_orders_user;
+		END IF;
+	END $$`).Error
+	if err != nil {
+		log.Fatalf("Failed to migrate database schemas: %v", err)
+	}
+
+	err = DB.AutoMigrate(
 		&models.User{}, &models.Product{},
 		&models.Order{}, &models.OrderItem{}, &models.Address{},
+		&models.RefreshToken{}, &models.RevokedToken{}, &models.AdminInvitation{},
+		&models.Permission{}, &models.Role{}, &models.PasswordResetToken{},
+		&models.EmailVerificationToken{}, &models.LoginThrottle{}, &models.AccountLockout{},
+		&models.MFARecoveryCode{}, &models.APIKey{}, &models.Session{}, &models.ImpersonationAudit{},
+		&models.ExternalIdentity{}, &models.OIDCLoginRequest{}, &models.Category{},
+		&models.ProductOption{}, &models.ProductOptionValue{}, &models.ProductVariant{}, &models.ProductImage{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database schemas: %v", err)
 	}
 
+	// Orders placed before addresses were copied onto them still point at the address
+	// book. Copy the referenced address into both snapshots while it is still there.
+	err = DB.Exec(`UPDATE orders SET
+		shipping_first_name = a.first_name, shipping_last_name = a.last_name,
+		shipping_street_address = a.street_address, shipping_city = a.city, shipping_state = a.state,
+		shipping_zip_code = a.zip_code, shipping_country = a.country,
+		billing_first_name = a.first_name, billing_last_name = a.last_name,
+		billing_street_address = a.street_address, billing_city = a.city, billing_state = a.state,
+		billing_zip_code = a.zip_code, billing_country = a.country
+	FROM addresses a
+	WHERE a.id = orders.address_id AND orders.shipping_street_address IS NULL`).Error
+	if err != nil {
+		log.Fatalf("Failed to migrate database schemas: %v", err)
+	}
+
+	// The search vector used to be generated from the product's category name, which
+	// has since moved to the categories table. Triggers maintain it instead.
+	err = DB.Exec(`DO $$ BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'products' AND column_name = 'search_vector' AND is_generated = 'ALWAYS') THEN
+			ALTER TABLE products DROP COLUMN search_vector;
+		END IF;
+	END $$`).Error
+	if err != nil {
+		log.Fatalf("Failed to migrate database schemas: %v", err)
+	}
+
+	if DB.Migrator().HasColumn("products", "category") {
+		if err := migrateProductCategories(); err != nil {
+			log.Fatalf("Failed to migrate product categories: %v", err)
+		}
+	}
+
+	// Product search ranks matches in the name above the category and the description,
+	// and falls back to trigram similarity for misspelled queries.
+	for _, statement := range []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+		`CREATE OR REPLACE FUNCTION products_search_vector() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_vector :=
+				setweight(to_tsvector('english', coalesce(NEW.name, '')), 'A') ||
+				setweight(to_tsvector('english', coalesce((SELECT name FROM categories WHERE id = NEW.category_id), '')), 'B') ||
+				setweight(to_tsvector('english', coalesce(NEW.description, '')), 'C');
+			RETURN NEW;
+		END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS products_search_vector ON products`,
+		`CREATE TRIGGER products_search_vector BEFORE INSERT OR UPDATE OF name, description, category_id ON products
+			FOR EACH ROW EXECUTE FUNCTION products_search_vector()`,
+		`CREATE OR REPLACE FUNCTION categories_search_vector() RETURNS trigger AS $$
+		BEGIN
+			UPDATE products SET category_id = category_id WHERE category_id = NEW.id;
+			RETURN NULL;
+		END $$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS categories_search_vector ON categories`,
+		`CREATE TRIGGER categories_search_vector AFTER UPDATE OF name ON categories
+			FOR EACH ROW EXECUTE FUNCTION categories_search_vector()`,
+		`UPDATE products SET name = name WHERE search_vector IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_categories_name_trgm ON categories USING GIN (name gin_trgm_ops)`,
+	} {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Fatalf("Failed to migrate database schemas: %v", err)
+		}
+	}
+
 	log.Println("Database schemas migrated successfully.")
 }
 
+// migrateProductCategories replaces the free-text category of each product with a
+// reference to a top-level category of the same name, then drops the old column.
+// Names that only differ in case or punctuation end up in the same category.
+func migrateProductCategories() error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var names []string
+		if err := tx.Table("products").Where("category_id IS NULL").Distinct().Pluck("category", &names).Error; err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			categoryName := strings.TrimSpace(name)
+			if models.Slugify(categoryName) == "" {
+				categoryName = "Uncategorized"
+			}
+
+			category := models.Category{Name: categoryName, Slug: models.Slugify(categoryName)}
+			if err := tx.Where("slug = ?", category.Slug).FirstOrCreate(&category).Error; err != nil {
+				return err
+			}
+
+			err := tx.Table("products").Where("category_id IS NULL AND category = ?", name).Update("category_id", category.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec("ALTER TABLE products DROP COLUMN category").Error
+	})
+}
+
 // SetMockDB is used for testing to set a mock DB.
 func SetMockDB(mockDB *gorm.DB) {
 	DB = mockDB
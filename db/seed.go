@@ -0,0 +1,55 @@
+package db
+
+import (
+	"log"
+
+	"github.com/cgzirim/ecommerce-api/models"
+	"gorm.io/gorm"
+)
+
+// SeedRolesAndPermissions makes sure every known permission and built-in role exists.
+// The admin role is always granted every permission; other built-in roles are only
+// given their default permissions when first created, so edits made by admins stick.
+func SeedRolesAndPermissions() {
+	if DB == nil {
+		log.Fatal("Database connection is not initialized. Call OpenDbConnection first.")
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		permissions := make(map[string]models.Permission)
+		for _, permission := range models.DefaultPermissions {
+			if err := tx.Where(models.Permission{Name: permission.Name}).Attrs(permission).FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+			permissions[permission.Name] = permission
+		}
+
+		for name, permissionNames := range models.DefaultRolePermissions {
+			var role models.Role
+			result := tx.Where(models.Role{Name: name}).FirstOrCreate(&role)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if result.RowsAffected == 0 && name != models.RoleAdmin {
+				continue
+			}
+
+			rolePermissions := make([]models.Permission, 0, len(permissionNames))
+			for _, permissionName := range permissionNames {
+				rolePermissions = append(rolePermissions, permissions[permissionName])
+			}
+
+			if err := tx.Model(&role).Association("Permissions").Replace(rolePermissions); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to seed roles and permissions: %v", err)
+	}
+
+	log.Println("Roles and permissions seeded successfully.")
+}
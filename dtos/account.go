@@ -0,0 +1,22 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/models"
+)
+
+// AccountDataExport represents the personal data held about a user, as returned by
+// the data export endpoint
+type AccountDataExport struct {
+	ExportedAt         time.Time                 `json:"exported_at"`
+	Profile            models.User               `json:"profile"`
+	Addresses          []models.Address          `json:"addresses"`
+	Orders             []models.Order            `json:"orders"`
+	ExternalIdentities []models.ExternalIdentity `json:"external_identities"`
+}
+
+// DeleteAccountRequest represents the expected request body for deleting the current user's account
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required" example:"securepassword"`
+}
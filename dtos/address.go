@@ -1,25 +1,46 @@
 package dtos
 
-// CreateAddressRequest represents the expected request body for creating an address
+// CreateAddressRequest represents the expected request body for creating or replacing an
+// address. Whether State and ZipCode are required depends on the country.
 type CreateAddressRequest struct {
-	FirstName     string `json:"first_name" binding:"required"`
-	LastName      string `json:"last_name" binding:"required"`
-	City          string `json:"city" binding:"required"`
-	Country       string `json:"country" binding:"required"`
-	ZipCode       string `json:"zip_code" binding:"required"`
-	StreetAddress string `json:"street_address" binding:"required"`
+	FirstName         string `json:"first_name" binding:"required"`
+	LastName          string `json:"last_name" binding:"required"`
+	City              string `json:"city" binding:"required"`
+	State             string `json:"state" example:"CA"`
+	Country           string `json:"country" binding:"required" example:"US"`
+	ZipCode           string `json:"zip_code" example:"94105"`
+	StreetAddress     string `json:"street_address" binding:"required"`
+	IsDefaultShipping bool   `json:"is_default_shipping" example:"true"`
+	IsDefaultBilling  bool   `json:"is_default_billing" example:"false"`
+}
+
+// UpdateAddressRequest represents the expected request body for partially updating an
+// address. Omitted fields are left unchanged.
+type UpdateAddressRequest struct {
+	FirstName         *string `json:"first_name" binding:"omitempty,min=1"`
+	LastName          *string `json:"last_name" binding:"omitempty,min=1"`
+	City              *string `json:"city" binding:"omitempty,min=1"`
+	State             *string `json:"state" example:"CA"`
+	Country           *string `json:"country" binding:"omitempty,min=1" example:"US"`
+	ZipCode           *string `json:"zip_code" example:"94105"`
+	StreetAddress     *string `json:"street_address" binding:"omitempty,min=1"`
+	IsDefaultShipping *bool   `json:"is_default_shipping" example:"true"`
+	IsDefaultBilling  *bool   `json:"is_default_billing" example:"false"`
 }
 
 // AddressDetail represents the response body for a successful address creation
 type AddressDetail struct {
-	ID            uint   `json:"id"`
-	FirstName     string `json:"first_name"`
-	LastName      string `json:"last_name"`
-	City          string `json:"city"`
-	Country       string `json:"country"`
-	ZipCode       string `json:"zip_code"`
-	StreetAddress string `json:"street_address"`
-	UserID        uint   `json:"user_id"`
-	CreatedAt     string `json:"created_at" example:"2024-12-26T01:59:44.840049+01:00"`
-	UpdatedAt     string `json:"updated_at" example:"2024-12-26T01:59:44.840049+01:00"`
+	ID                uint   `json:"id"`
+	FirstName         string `json:"first_name"`
+	LastName          string `json:"last_name"`
+	City              string `json:"city"`
+	State             string `json:"state"`
+	Country           string `json:"country"`
+	ZipCode           string `json:"zip_code"`
+	StreetAddress     string `json:"street_address"`
+	IsDefaultShipping bool   `json:"is_default_shipping"`
+	IsDefaultBilling  bool   `json:"is_default_billing"`
+	UserID            uint   `json:"user_id"`
+	CreatedAt         string `json:"created_at" example:"2024-12-26T01:59:44.840049+01:00"`
+	UpdatedAt         string `json:"updated_at" example:"2024-12-26T01:59:44.840049+01:00"`
 }
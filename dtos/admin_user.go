@@ -0,0 +1,49 @@
+package dtos
+
+import (
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/models"
+)
+
+// UserListResponse represents the response body for a successful user listing
+type UserListResponse struct {
+	Page       int           `json:"page" example:"1"`
+	PageSize   int           `json:"page_size" example:"10"`
+	TotalCount int64         `json:"total_count" example:"42"`
+	TotalPages int64         `json:"total_pages" example:"5"`
+	Users      []models.User `json:"users"`
+}
+
+// UserDetailResponse represents the response body for an admin's view of a single user
+type UserDetailResponse struct {
+	User        models.User      `json:"user"`
+	OrderCount  int64            `json:"order_count" example:"7"`
+	OrderCounts map[string]int64 `json:"order_counts"`
+}
+
+// SuspendUserRequest represents the expected request body for suspending a user
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"max=500" example:"Chargeback fraud under investigation"`
+}
+
+// ImpersonateUserRequest represents the expected request body for impersonating a user
+type ImpersonateUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500" example:"Ticket #4521: customer cannot check out"`
+}
+
+// ImpersonationResponse represents the response body for a successful impersonation
+type ImpersonationResponse struct {
+	AccessToken string      `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	ExpiresAt   time.Time   `json:"expires_at"`
+	User        models.User `json:"user"`
+}
+
+// ImpersonationAuditListResponse represents the response body for a successful impersonation audit listing
+type ImpersonationAuditListResponse struct {
+	Page       int                         `json:"page" example:"1"`
+	PageSize   int                         `json:"page_size" example:"10"`
+	TotalCount int64                       `json:"total_count" example:"42"`
+	TotalPages int64                       `json:"total_pages" example:"5"`
+	Entries    []models.ImpersonationAudit `json:"entries"`
+}
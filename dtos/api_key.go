@@ -0,0 +1,17 @@
+package dtos
+
+import "github.com/cgzirim/ecommerce-api/models"
+
+// CreateAPIKeyRequest represents the expected request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100" example:"ERP product sync"`
+	Scopes        []string `json:"scopes" binding:"required,min=1" example:"products:create,products:update"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,gt=0" example:"90"`
+}
+
+// APIKeyCreatedResponse represents the response body for a newly created API key.
+// The key is only ever returned here.
+type APIKeyCreatedResponse struct {
+	APIKey models.APIKey `json:"api_key"`
+	Key    string        `json:"key" example:"eck_q3X9rK2mW7dTzP1vB8nLcA5sYe6uHfJ0q3X9rK2mW7d"`
+}
@@ -0,0 +1,20 @@
+package dtos
+
+import "github.com/cgzirim/ecommerce-api/models"
+
+// CategoryRequest represents the expected request body for creating or replacing a
+// category. The slug is derived from the name if omitted, and categories without a
+// parent are top-level categories.
+type CategoryRequest struct {
+	Name        string `json:"name" binding:"required,max=255" example:"T-Shirts"`
+	Slug        string `json:"slug" binding:"omitempty,max=255" example:"t-shirts"`
+	Description string `json:"description" example:"Short and long sleeved shirts"`
+	ParentID    *uint  `json:"parent_id" example:"1"`
+}
+
+// CategoryProductListResponse represents the response body for listing the products in
+// a category and its descendants
+type CategoryProductListResponse struct {
+	Category models.Category `json:"category"`
+	ProductListResponse
+}
@@ -0,0 +1,16 @@
+package dtos
+
+import "github.com/cgzirim/ecommerce-api/models"
+
+// CreateInvitationRequest represents the expected request body for inviting an admin
+type CreateInvitationRequest struct {
+	Email          string `json:"email" binding:"omitempty,email" example:"new.admin@example.com"`
+	ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,gt=0,max=720" example:"72"`
+}
+
+// InvitationCreatedResponse represents the response body for a newly created invitation.
+// The token is only ever returned here.
+type InvitationCreatedResponse struct {
+	Invitation models.AdminInvitation `json:"invitation"`
+	Token      string                 `json:"token" example:"q3X9rK2mW7dTzP1vB8nLcA5sYe6uHfJ0"`
+}
@@ -0,0 +1,34 @@
+package dtos
+
+// MFAChallengeResponse represents the response body for a login that needs a second factor
+type MFAChallengeResponse struct {
+	Msg                   string `json:"msg" example:"MFA code required"`
+	MFARequired           bool   `json:"mfa_required" example:"true"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required" example:"false"`
+	MFAToken              string `json:"mfa_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+// MFAEnrollmentResponse represents the response body for starting MFA enrollment
+type MFAEnrollmentResponse struct {
+	Secret        string   `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	OTPAuthURI    string   `json:"otpauth_uri" example:"otpauth://totp/E-Commerce%20API:admin@example.com?secret=JBSWY3DPEHPK3PXP&issuer=E-Commerce+API"`
+	RecoveryCodes []string `json:"recovery_codes" example:"q3X9rK2m,W7dTzP1v"`
+}
+
+// MFACodeRequest represents the expected request body for confirming or disabling MFA
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+// MFALoginRequest represents the expected request body for completing a login with a
+// second factor. Either code or recovery_code must be provided.
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" example:"123456"`
+	RecoveryCode string `json:"recovery_code" example:"q3X9rK2m"`
+}
+
+// MFAEnrollLoginRequest represents the expected request body for enrolling in MFA during login
+type MFAEnrollLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
@@ -0,0 +1,6 @@
+package dtos
+
+// OIDCProvidersResponse represents the response body for listing the identity providers users can log in with
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers" example:"google"`
+}
@@ -4,16 +4,21 @@ import (
 	"github.com/cgzirim/ecommerce-api/models"
 )
 
-// OrderItemRequest represents the request to add an item to an order
+// OrderItemRequest represents the request to add an item to an order. VariantID is
+// required for products with variants.
 type OrderItemRequest struct {
 	ProductID uint `json:"product_id" binding:"required"`
+	VariantID uint `json:"variant_id" example:"3"`
 	Quantity  int  `json:"quantity" binding:"required,gt=0"`
 }
 
-// CreateOrderRequest represents the expected request body for creating an order
+// CreateOrderRequest represents the expected request body for creating an order. The
+// user's default shipping address is used if AddressID is omitted, and the billing
+// address falls back to the default billing address, then to the shipping address.
 type CreateOrderRequest struct {
-	AddressID  uint               `json:"address_id" binding:"required"`
-	OrderItems []OrderItemRequest `json:"order_items" binding:"required,min=1"`
+	AddressID        uint               `json:"address_id"`
+	BillingAddressID uint               `json:"billing_address_id"`
+	OrderItems       []OrderItemRequest `json:"order_items" binding:"required,min=1"`
 }
 
 // OrderDetail represents the response body for a successful order creation
@@ -0,0 +1,13 @@
+package dtos
+
+// ForgotPasswordRequest represents the expected request body for requesting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
+
+// ResetPasswordRequest represents the expected request body for resetting a password
+type ResetPasswordRequest struct {
+	Token           string `json:"token" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"password_confirm" binding:"required"`
+}
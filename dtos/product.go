@@ -17,7 +17,7 @@ type CreateProductRequest struct {
 	Description string  `json:"description" binding:"required"`
 	Price       float64 `json:"price" binding:"required,gt=0"`
 	Stock       int     `json:"stock" binding:"required,gt=-1"`
-	Category    string  `json:"category" binding:"required"`
+	CategoryID  uint    `json:"category_id" binding:"required" example:"1"`
 }
 
 // PatchProductRequest represents the expected request body for updating a product
@@ -26,5 +26,33 @@ type PatchProductRequest struct {
 	Description string  `json:"description" binding:"omitempty"`
 	Price       float64 `json:"price" binding:"omitempty,gt=0"`
 	Stock       int     `json:"stock" binding:"omitempty,gt=-1"`
-	Category    string  `json:"category" binding:"omitempty"`
+	CategoryID  uint    `json:"category_id" binding:"omitempty" example:"1"`
+}
+
+// ProductSearchResult is a product matching a search, with its relevance and an excerpt
+// of its description in which the matching words are wrapped in <mark> tags.
+type ProductSearchResult struct {
+	models.Product
+	Rank    float64 `json:"rank" example:"0.6"`
+	Snippet string  `json:"snippet" example:"A <mark>leather</mark> jacket with a quilted lining"`
+}
+
+// CategoryFacet counts the products in a category that match a search
+type CategoryFacet struct {
+	Category string `json:"category" example:"Clothing"`
+	Slug     string `json:"slug" example:"clothing"`
+	Count    int64  `json:"count" example:"12"`
+}
+
+// ProductSearchResponse represents the response body for a product search. Fuzzy is set
+// when nothing matched the words exactly and results are for similarly spelled words.
+type ProductSearchResponse struct {
+	Query      string                `json:"query" example:"leather jack"`
+	Fuzzy      bool                  `json:"fuzzy" example:"false"`
+	Page       int                   `json:"page" example:"1"`
+	PageSize   int                   `json:"page_size" example:"10"`
+	TotalCount int64                 `json:"total_count" example:"3"`
+	TotalPages int                   `json:"total_pages" example:"1"`
+	Results    []ProductSearchResult `json:"results"`
+	Facets     []CategoryFacet       `json:"facets"`
 }
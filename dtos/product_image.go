@@ -0,0 +1,12 @@
+package dtos
+
+// UpdateProductImageRequest represents the expected request body for updating an image
+type UpdateProductImageRequest struct {
+	AltText string `json:"alt_text" binding:"max=255" example:"Oxford shirt in blue, front view"`
+}
+
+// ReorderProductImagesRequest represents the expected request body for reordering the
+// images of a product. It lists the IDs of all of the product's images in their new order.
+type ReorderProductImagesRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required" example:"3,1,2"`
+}
@@ -0,0 +1,33 @@
+package dtos
+
+// ProductOptionRequest is an option of a product and the values it comes in, in the
+// order they should be shown
+type ProductOptionRequest struct {
+	Name   string   `json:"name" binding:"required,max=50" example:"Size"`
+	Values []string `json:"values" binding:"required,min=1,dive,required,max=50" example:"S,M,L"`
+}
+
+// SetProductOptionsRequest represents the expected request body for replacing the
+// options of a product
+type SetProductOptionsRequest struct {
+	Options []ProductOptionRequest `json:"options" binding:"required,max=3,dive"`
+}
+
+// GenerateProductVariantsRequest represents the request body for generating the
+// variants of a product. Every field is optional: SKUs start with the product name,
+// and new variants get the product's price, no stock and no weight.
+type GenerateProductVariantsRequest struct {
+	SKUPrefix string  `json:"sku_prefix" binding:"omitempty,max=32" example:"OXFORD"`
+	Price     float64 `json:"price" binding:"omitempty,gt=0" example:"39.99"`
+	Stock     int     `json:"stock" binding:"omitempty,gt=-1" example:"10"`
+	Weight    float64 `json:"weight" binding:"omitempty,gte=0" example:"0.25"`
+}
+
+// PatchProductVariantRequest represents the expected request body for updating a
+// variant. Stock and Weight are pointers so that they can be set to 0.
+type PatchProductVariantRequest struct {
+	SKU    string   `json:"sku" binding:"omitempty,max=64" example:"OXFORD-M-BLUE"`
+	Price  float64  `json:"price" binding:"omitempty,gt=0" example:"39.99"`
+	Stock  *int     `json:"stock" binding:"omitempty,gt=-1" example:"0"`
+	Weight *float64 `json:"weight" binding:"omitempty,gte=0" example:"0.25"`
+}
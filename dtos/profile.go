@@ -0,0 +1,26 @@
+package dtos
+
+import "github.com/cgzirim/ecommerce-api/models"
+
+// UpdateProfileRequest represents the expected request body for updating the current
+// user's profile. Omitted fields are left unchanged.
+type UpdateProfileRequest struct {
+	FirstName *string `json:"first_name" binding:"omitempty,min=1,max=100" example:"John"`
+	LastName  *string `json:"last_name" binding:"omitempty,min=1,max=100" example:"Doe"`
+	Email     *string `json:"email" binding:"omitempty,email" example:"new.address@example.com"`
+}
+
+// ProfileResponse represents the response body for a profile update. A changed email
+// only takes effect once the new address is verified; until then it is PendingEmail.
+type ProfileResponse struct {
+	Msg          string      `json:"msg" example:"Profile updated successfully"`
+	User         models.User `json:"user"`
+	PendingEmail string      `json:"pending_email,omitempty" example:"new.address@example.com"`
+}
+
+// ChangePasswordRequest represents the expected request body for changing the current user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"password_confirm" binding:"required"`
+}
@@ -0,0 +1,13 @@
+package dtos
+
+// RoleRequest represents the expected request body for creating or updating a role
+type RoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=50" example:"warehouse_clerk"`
+	Description string   `json:"description" example:"Fulfils orders"`
+	Permissions []string `json:"permissions" binding:"required" example:"orders:read_all,orders:update_status"`
+}
+
+// AssignRolesRequest represents the expected request body for assigning roles to a user
+type AssignRolesRequest struct {
+	Roles []string `json:"roles" binding:"required" example:"warehouse_clerk"`
+}
@@ -0,0 +1,13 @@
+package dtos
+
+// RefreshTokenRequest represents the expected request body for exchanging a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+// TokenRefreshResponse represents the response body for a successful token refresh
+type TokenRefreshResponse struct {
+	Msg          string `json:"msg" example:"Token refreshed successfully"`
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
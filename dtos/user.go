@@ -21,9 +21,9 @@ type AdminRegistrationRequest struct {
 	Email           string `json:"email" binding:"required,email"`
 	FirstName       string `json:"first_name" binding:"required"`
 	LastName        string `json:"last_name" binding:"required"`
-	Password        string `json:"password" binding:"required,min=6"`
-	PasswordConfirm string `json:"password_confirm" binding:"required,min=6"`
-	SecretKey       string `json:"secret_key" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"password_confirm" binding:"required"`
+	InvitationToken string `json:"invitation_token" binding:"required"`
 }
 
 // CustomerRegistrationRequest represents the expected request body for registering a customer
@@ -31,8 +31,8 @@ type CustomerRegistrationRequest struct {
 	Email           string `json:"email" binding:"required,email"`
 	FirstName       string `json:"first_name" binding:"required"`
 	LastName        string `json:"last_name" binding:"required"`
-	Password        string `json:"password" binding:"required,min=6"`
-	PasswordConfirm string `json:"password_confirm" binding:"required,min=6"`
+	Password        string `json:"password" binding:"required"`
+	PasswordConfirm string `json:"password_confirm" binding:"required"`
 }
 
 // RegistrationSuccessResponse represents the response body for a successful registration
@@ -47,3 +47,8 @@ type RegistrationSuccessResponse struct {
 type ErrorResponse struct {
 	Error string `json:"error" example:"Validation failed"`
 }
+
+// MessageResponse represents a response body that only carries a message
+type MessageResponse struct {
+	Msg string `json:"msg" example:"Logged out successfully"`
+}
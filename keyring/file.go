@@ -0,0 +1,120 @@
+package keyring
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cgzirim/ecommerce-api/utils"
+)
+
+// LoadDir loads every <kid>.pem file in dir. Files may hold a PKCS#8 or PKCS#1 private
+// key, or a PKIX public key for a retired key that should only verify tokens. New tokens
+// are signed with the private key named signingKeyID or, when it is empty, with the
+// private key whose ID sorts last, so timestamped IDs select the newest key.
+func LoadDir(dir, signingKeyID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var keys []*Key
+	var signing *Key
+
+	for _, path := range paths {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if key.Private != nil && (key.ID == signingKeyID || signingKeyID == "") {
+			signing = key
+		}
+		keys = append(keys, key)
+	}
+
+	if signing == nil {
+		if signingKeyID != "" {
+			return nil, fmt.Errorf("no private key with ID %q found in %s", signingKeyID, dir)
+		}
+		return nil, fmt.Errorf("no private signing key found in %s", dir)
+	}
+
+	others := make([]*Key, 0, len(keys)-1)
+	for _, key := range keys {
+		if key != signing {
+			others = append(others, key)
+		}
+	}
+
+	return New(signing, others...)
+}
+
+// LoadFromEnv loads the keyring from JWT_KEYS_DIR, signing with JWT_SIGNING_KEY_ID if set.
+func LoadFromEnv() (*Keyring, error) {
+	return LoadDir(utils.GetEnv("JWT_KEYS_DIR", "keys"), utils.GetEnv("JWT_SIGNING_KEY_ID", ""))
+}
+
+// WriteKey saves a private key to dir as <kid>.pem in PKCS#8 form.
+func WriteKey(dir string, key *Key) (string, error) {
+	if key.Private == nil {
+		return "", errors.New("only private keys can be written")
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, key.ID+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func loadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s does not contain a PEM block", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s contains an unsupported %q PEM block", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return NewKey(strings.TrimSuffix(filepath.Base(path), ".pem"), parsed)
+}
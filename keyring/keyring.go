@@ -0,0 +1,188 @@
+// Package keyring holds the asymmetric keys used to sign and verify JWTs. Tokens are
+// signed with a single current key and carry its ID in the kid header; older keys
+// stay in the keyring so tokens they signed remain valid while keys are rotated.
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// MinRSAKeyBits is the smallest RSA modulus accepted for RS256 keys.
+const MinRSAKeyBits = 2048
+
+// Key is a single signing or verification key.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// Private is nil for keys that are only kept to verify tokens they signed earlier.
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// NewKey builds a key from a private or public RSA or Ed25519 key.
+func NewKey(id string, key interface{}) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("key ID is required")
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < MinRSAKeyBits {
+			return nil, fmt.Errorf("RSA key %s is %d bits, at least %d are required", id, k.N.BitLen(), MinRSAKeyBits)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		if k.N.BitLen() < MinRSAKeyBits {
+			return nil, fmt.Errorf("RSA key %s is %d bits, at least %d are required", id, k.N.BitLen(), MinRSAKeyBits)
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: EdDSA, Private: k, Public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: EdDSA, Public: k}, nil
+	default:
+		return nil, fmt.Errorf("key %s has unsupported type %T", id, key)
+	}
+}
+
+// GenerateKey creates a new private key for the given algorithm, "RS256" or "EdDSA".
+func GenerateKey(id, alg string) (*Key, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err := rsa.GenerateKey(rand.Reader, MinRSAKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(id, privateKey)
+	case EdDSA.Alg():
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return NewKey(id, privateKey)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+// Keyring is a set of keys, one of which signs new tokens.
+type Keyring struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// New builds a keyring that signs with signing and also verifies tokens signed by any
+// of the other keys.
+func New(signing *Key, others ...*Key) (*Keyring, error) {
+	if signing == nil || signing.Private == nil {
+		return nil, errors.New("a private signing key is required")
+	}
+
+	k := &Keyring{signing: signing, keys: map[string]*Key{signing.ID: signing}}
+	for _, key := range others {
+		if _, exists := k.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key ID %s", key.ID)
+		}
+		k.keys[key.ID] = key
+	}
+
+	return k, nil
+}
+
+// SigningKey returns the key used to sign new tokens.
+func (k *Keyring) SigningKey() *Key {
+	return k.signing
+}
+
+// Keys returns every key in the keyring, including the signing key.
+func (k *Keyring) Keys() []*Key {
+	keys := make([]*Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Sign signs claims with the current signing key.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	token.Header["kid"] = k.signing.ID
+
+	return token.SignedString(k.signing.Private)
+}
+
+// Parse verifies a token against the key named by its kid header and returns its
+// claims. The token's alg must match the algorithm of that key.
+func (k *Keyring) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+
+		return key.Public, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+
+	return claims, nil
+}
+
+var (
+	mu      sync.RWMutex
+	current *Keyring
+)
+
+// Set installs the keyring used by Sign and Parse.
+func Set(k *Keyring) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = k
+}
+
+// Current returns the installed keyring, or nil if none has been set.
+func Current() *Keyring {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Sign signs claims with the installed keyring.
+func Sign(claims jwt.Claims) (string, error) {
+	k := Current()
+	if k == nil {
+		return "", errors.New("no JWT signing keys are configured")
+	}
+
+	return k.Sign(claims)
+}
+
+// Parse verifies a token with the installed keyring.
+func Parse(tokenString string) (jwt.MapClaims, error) {
+	k := Current()
+	if k == nil {
+		return nil, errors.New("no JWT signing keys are configured")
+	}
+
+	return k.Parse(tokenString)
+}
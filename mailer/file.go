@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/utils"
+)
+
+// FileSender writes every message to its own file in Dir, for local development and tests.
+type FileSender struct {
+	Dir string
+}
+
+// Send writes the message to a new .eml file in the sender's directory.
+func (s FileSender) Send(msg Message) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), recipient)
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+
+	return os.WriteFile(filepath.Join(s.Dir, name), []byte(content), 0o644)
+}
+
+// NewSenderFromEnv builds the sender selected by the MAILER environment variable:
+// "file" writes messages to MAILER_DIR, anything else logs them.
+func NewSenderFromEnv() Sender {
+	switch utils.GetEnv("MAILER", "log") {
+	case "file":
+		return FileSender{Dir: utils.GetEnv("MAILER_DIR", "mail")}
+	default:
+		return LogSender{}
+	}
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import "log"
+
+// Message is an email to be delivered to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email messages.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// LogSender writes messages to the application log instead of delivering them.
+type LogSender struct{}
+
+// Send logs the message.
+func (LogSender) Send(msg Message) error {
+	log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+var sender Sender = LogSender{}
+
+// SetSender replaces the sender used by Send, e.g. with a real mail provider or a test double.
+func SetSender(s Sender) {
+	sender = s
+}
+
+// Send delivers a message through the configured sender.
+func Send(msg Message) error {
+	return sender.Send(msg)
+}
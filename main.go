@@ -1,12 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"github.com/cgzirim/ecommerce-api/controllers"
 	"github.com/cgzirim/ecommerce-api/db"
 	_ "github.com/cgzirim/ecommerce-api/docs"
+	"github.com/cgzirim/ecommerce-api/keyring"
+	"github.com/cgzirim/ecommerce-api/mailer"
 	"github.com/cgzirim/ecommerce-api/middleware"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/cgzirim/ecommerce-api/oidc"
+	"github.com/cgzirim/ecommerce-api/passwordpolicy"
+	"github.com/cgzirim/ecommerce-api/storage"
+	"github.com/cgzirim/ecommerce-api/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	swaggerFiles "github.com/swaggo/files"
@@ -21,17 +31,57 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
 	godotenv.Load()
 
+	if len(os.Args) > 1 && os.Args[1] == "generate-jwt-key" {
+		generateJwtKey(os.Args[2:])
+		return
+	}
+
+	mailer.SetSender(mailer.NewSenderFromEnv())
+
+	keys, err := keyring.LoadFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v (run `go run main.go generate-jwt-key` to create one)", err)
+	}
+	keyring.Set(keys)
+
+	files, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure file storage: %v", err)
+	}
+	storage.Set(files)
+
+	policy, err := passwordpolicy.LoadFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+	passwordpolicy.Set(policy)
+
+	providers, err := oidc.LoadFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load OIDC providers: %v", err)
+	}
+	oidc.Set(providers...)
+
 	db.OpenDbConnection()
 	db.MigrateDBSchemas()
+	db.SeedRolesAndPermissions()
+
+	if len(os.Args) > 1 && os.Args[1] == "invite-admin" {
+		inviteAdmin(os.Args[2:])
+		return
+	}
 
 	router := SetupRouter()
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	err := router.Run(":8080")
+	err = router.Run(":8080")
 	if err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
@@ -40,26 +90,120 @@ func main() {
 func SetupRouter() *gin.Engine {
 	r := gin.Default()
 
+	r.GET("/.well-known/jwks.json", controllers.JWKS)
+
+	if local, ok := storage.Get().(*storage.Local); ok {
+		r.Static(storage.LocalRoute, local.Dir)
+	}
+
 	v1 := r.Group("/v1")
 	{
 		v1.Use(middleware.LoadAuthUserMiddleware())
 
 		// Auth routes
 		v1.POST("/login", controllers.LoginUser)
+		v1.POST("/login/mfa", controllers.VerifyMFALogin)
+		v1.POST("/login/mfa/enroll", controllers.EnrollMFAForLogin)
+		v1.GET("/login/oidc", controllers.ListOIDCProviders)
+		v1.GET("/login/oidc/:provider", controllers.StartOIDCLogin)
+		v1.GET("/login/oidc/:provider/callback", controllers.OIDCCallback)
 		v1.POST("/register", controllers.RegisterCustomer)
 		v1.POST("/register/admin", controllers.RegisterAdmin)
+		v1.POST("/token/refresh", controllers.RefreshAccessToken)
+		v1.POST("/logout", controllers.Logout)
+		v1.POST("/logout/all", controllers.LogoutAllSessions)
+		v1.POST("/password/forgot", controllers.ForgotPassword)
+		v1.POST("/password/reset", controllers.ResetPassword)
+		v1.GET("/verify-email", controllers.VerifyEmail)
+		v1.POST("/verify-email/resend", controllers.ResendVerificationEmail)
+
+		// Admin routes
+		v1.POST("/admin/invitations", controllers.CreateAdminInvitation)
+		v1.GET("/admin/invitations", controllers.ListAdminInvitations)
+		v1.DELETE("/admin/invitations/:id", controllers.RevokeAdminInvitation)
+
+		roleAdmin := v1.Group("/admin", middleware.RequirePermission(models.PermissionRolesManage))
+		{
+			roleAdmin.GET("/permissions", controllers.ListPermissions)
+			roleAdmin.GET("/roles", controllers.ListRoles)
+			roleAdmin.POST("/roles", controllers.CreateRole)
+			roleAdmin.PUT("/roles/:id", controllers.UpdateRole)
+			roleAdmin.DELETE("/roles/:id", controllers.DeleteRole)
+			roleAdmin.PUT("/users/:id/roles", controllers.AssignUserRoles)
+		}
+
+		userAdmin := v1.Group("/admin", middleware.RequirePermission(models.PermissionUsersManage))
+		{
+			userAdmin.GET("/users", controllers.ListUsers)
+			userAdmin.GET("/users/:id", controllers.GetUser)
+			userAdmin.PATCH("/users/:id/suspend", controllers.SuspendUser)
+			userAdmin.PATCH("/users/:id/reactivate", controllers.ReactivateUser)
+			userAdmin.GET("/lockouts", controllers.ListAccountLockouts)
+			userAdmin.POST("/lockouts/:id/unlock", controllers.UnlockAccountLockout)
+			userAdmin.POST("/users/:id/unlock", controllers.UnlockUser)
+			userAdmin.GET("/users/:id/sessions", controllers.ListUserSessions)
+			userAdmin.DELETE("/sessions/:id", controllers.RevokeUserSession)
+			userAdmin.GET("/impersonations", controllers.ListImpersonationAudits)
+		}
+
+		impersonation := v1.Group("/admin", middleware.RequirePermission(models.PermissionUsersImpersonate))
+		{
+			impersonation.POST("/users/:id/impersonate", controllers.ImpersonateUser)
+		}
 
 		// User routes
+		v1.GET("/users/me", controllers.GetProfile)
+		v1.PATCH("/users/me", controllers.UpdateProfile)
+		v1.DELETE("/users/me", controllers.DeleteAccount)
+		v1.POST("/users/me/password", controllers.ChangePassword)
+		v1.GET("/users/me/export", controllers.ExportAccountData)
+
+		v1.GET("/users/sessions", controllers.ListSessions)
+		v1.DELETE("/users/sessions/:id", controllers.RevokeSession)
+
+		v1.POST("/users/api-keys", controllers.CreateAPIKey)
+		v1.GET("/users/api-keys", controllers.ListAPIKeys)
+		v1.DELETE("/users/api-keys/:id", controllers.RevokeAPIKey)
+
+		v1.POST("/users/mfa/enroll", controllers.EnrollMFA)
+		v1.POST("/users/mfa/confirm", controllers.ConfirmMFA)
+		v1.DELETE("/users/mfa", controllers.DisableMFA)
+
 		v1.GET("/users/addresses", controllers.ListAddresses)
 		v1.POST("/users/addresses", controllers.CreateAddress)
+		v1.GET("/users/addresses/:id", controllers.GetAddress)
+		v1.PUT("/users/addresses/:id", controllers.ReplaceAddress)
+		v1.PATCH("/users/addresses/:id", controllers.UpdateAddress)
+		v1.DELETE("/users/addresses/:id", controllers.DeleteAddress)
 
 		// Product routes
 		v1.GET("/products", controllers.ListProducts)
+		v1.GET("/products/search", controllers.SearchProducts)
 		v1.GET("/products/:id", controllers.GetProductByID)
 		v1.POST("/products", controllers.CreateProduct)
 		v1.PUT("/products/:id", controllers.UpdateProduct)
 		v1.PATCH("/products/:id", controllers.PatchProduct)
 		v1.DELETE("/products/:id", controllers.DeleteProduct)
+		v1.PUT("/products/:id/options", controllers.SetProductOptions)
+		v1.POST("/products/:id/variants/generate", controllers.GenerateProductVariants)
+		v1.PATCH("/products/:id/variants/:variant_id", controllers.UpdateProductVariant)
+		v1.DELETE("/products/:id/variants/:variant_id", controllers.DeleteProductVariant)
+		v1.POST("/products/:id/images", controllers.UploadProductImage)
+		v1.PUT("/products/:id/images/order", controllers.ReorderProductImages)
+		v1.PATCH("/products/:id/images/:image_id", controllers.UpdateProductImage)
+		v1.DELETE("/products/:id/images/:image_id", controllers.DeleteProductImage)
+
+		// Category routes
+		v1.GET("/categories", controllers.ListCategories)
+		v1.GET("/categories/:slug", controllers.GetCategory)
+		v1.GET("/categories/:slug/products", controllers.ListCategoryProducts)
+
+		categoryAdmin := v1.Group("/categories", middleware.RequirePermission(models.PermissionCategoriesManage))
+		{
+			categoryAdmin.POST("", controllers.CreateCategory)
+			categoryAdmin.PUT("/:id", controllers.UpdateCategory)
+			categoryAdmin.DELETE("/:id", controllers.DeleteCategory)
+		}
 
 		// Order routes
 		v1.POST("/orders", controllers.CreateOrder)
@@ -71,3 +215,41 @@ func SetupRouter() *gin.Engine {
 
 	return r
 }
+
+// inviteAdmin issues an admin invitation from the command line, which is how the
+// first admin account is created: go run main.go invite-admin [email]
+func inviteAdmin(args []string) {
+	var email string
+	if len(args) > 0 {
+		email = args[0]
+	}
+
+	invitation, token, err := controllers.NewAdminInvitation(nil, email, controllers.ADMIN_INVITATION_TTL)
+	if err != nil {
+		log.Fatalf("Failed to create admin invitation: %v", err)
+	}
+
+	fmt.Printf("Admin invitation token (expires %s):\n%s\n", invitation.ExpiresAt.Format(time.RFC1123), token)
+}
+
+// generateJwtKey writes a new private key to JWT_KEYS_DIR, named with the current
+// time so that it becomes the signing key on the next start while older keys keep
+// verifying the tokens they signed: go run main.go generate-jwt-key [RS256|EdDSA]
+func generateJwtKey(args []string) {
+	alg := "EdDSA"
+	if len(args) > 0 {
+		alg = args[0]
+	}
+
+	key, err := keyring.GenerateKey(time.Now().UTC().Format("20060102T150405Z"), alg)
+	if err != nil {
+		log.Fatalf("Failed to generate JWT key: %v", err)
+	}
+
+	path, err := keyring.WriteKey(utils.GetEnv("JWT_KEYS_DIR", "keys"), key)
+	if err != nil {
+		log.Fatalf("Failed to write JWT key: %v", err)
+	}
+
+	fmt.Printf("Wrote %s key %s to %s\n", alg, key.ID, path)
+}
@@ -4,10 +4,10 @@ import (
 	"errors"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/cgzirim/ecommerce-api/db"
 	"github.com/cgzirim/ecommerce-api/models"
-	"github.com/cgzirim/ecommerce-api/utils"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
@@ -27,29 +27,97 @@ func LoadAuthUserMiddleware() gin.HandlerFunc {
 		}
 
 		c.Next()
+
+		if user != nil {
+			auditImpersonatedRequest(c, user)
+		}
 	}
 }
 
-// GetAuthenticatedUser retrieves the authenticated user from the context
+// GetAuthenticatedUser retrieves the authenticated user from the API key or the
+// Bearer token sent with the request
 func GetAuthenticatedUser(c *gin.Context) (*models.User, error) {
-	userID, err := GetUserIDFromJWT(c)
+	if c.GetHeader(APIKeyHeader) != "" {
+		return GetAPIKeyUser(c)
+	}
+
+	claims, err := GetClaimsFromJWT(c)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := claims["userID"].(float64)
+	if !ok {
+		return nil, errors.New("userID not found in token")
+	}
+
+	jti, _ := claims["jti"].(string)
+	revoked, err := IsTokenRevoked(jti)
 	if err != nil {
 		return nil, err
 	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	familyID, _ := claims["family"].(string)
+	if err := CheckSession(familyID); err != nil {
+		return nil, err
+	}
 
 	var user models.User
-	if err := db.DB.First(&user, userID).Error; err != nil {
+	if err := db.DB.First(&user, uint(userID)).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.IsAnonymized() {
 		return nil, errors.New("user not found")
 	}
+	if user.IsSuspended() {
+		return nil, errors.New("user is suspended")
+	}
+
+	issuedAt, _ := claims["iat"].(float64)
+	if user.TokensValidAfter != nil && int64(issuedAt) < user.TokensValidAfter.Unix() {
+		return nil, errors.New("token has been revoked")
+	}
+
+	if err := user.LoadPermissions(db.DB); err != nil {
+		return nil, err
+	}
+
+	impersonator, err := loadImpersonator(claims)
+	if err != nil {
+		return nil, err
+	}
+	if impersonator != nil {
+		c.Set("real_user", *impersonator)
+	}
+
+	c.Set("token_claims", claims)
 
 	return &user, nil
 }
 
 // GetUserIDFromJWT extracts the user ID from the JWT token in the Authorization header
 func GetUserIDFromJWT(c *gin.Context) (uint, error) {
+	claims, err := GetClaimsFromJWT(c)
+	if err != nil {
+		return 0, err
+	}
+
+	if userID, ok := claims["userID"].(float64); ok {
+		return uint(userID), nil
+	}
+
+	return 0, errors.New("userID not found in token")
+}
+
+// GetClaimsFromJWT verifies the access token in the Authorization header and returns its claims
+func GetClaimsFromJWT(c *gin.Context) (jwt.MapClaims, error) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		return 0, errors.New("authorization header is missing")
+		return nil, errors.New("authorization header is missing")
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
@@ -60,24 +128,23 @@ func GetUserIDFromJWT(c *gin.Context) (uint, error) {
 	// 	return 0, errors.New("invalid authorization header format")
 	// }
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-
-		jwtSecret := []byte(utils.GetEnv("JWT_SECRET", "!2E"))
-		return jwtSecret, nil
-	})
-
-	if err != nil || !token.Valid {
-		return 0, errors.New("invalid token")
+	claims, err := models.ParseJwtToken(tokenString)
+	if err != nil {
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		if userID, ok := claims["userID"].(float64); ok {
-			return uint(userID), nil
-		}
+	if tokenType, _ := claims["type"].(string); tokenType != models.TokenTypeAccess {
+		return nil, errors.New("token is not an access token")
 	}
 
-	return 0, errors.New("userID not found in token")
+	return claims, nil
+}
+
+// RevokeAccessToken puts the access token described by claims on the revocation list.
+func RevokeAccessToken(claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["userID"].(float64)
+	exp, _ := claims["exp"].(float64)
+
+	return RevokeToken(jti, uint(userID), time.Unix(int64(exp), 0))
 }
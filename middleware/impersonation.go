@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// loadImpersonator returns the admin named in the "act" claim of an impersonation
+// token, or nil if the token is not one. The admin must still be allowed to
+// impersonate, so suspending them or revoking the permission ends their impersonation
+// immediately.
+func loadImpersonator(claims jwt.MapClaims) (*models.User, error) {
+	act, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	actorID, ok := act["userID"].(float64)
+	if !ok {
+		return nil, errors.New("impersonator not found in token")
+	}
+
+	var actor models.User
+	if err := db.DB.Limit(1).Find(&actor, uint(actorID)).Error; err != nil {
+		return nil, err
+	}
+	if actor.ID == 0 || actor.IsAnonymized() || actor.IsSuspended() {
+		return nil, errors.New("impersonator is no longer active")
+	}
+
+	if err := actor.LoadPermissions(db.DB); err != nil {
+		return nil, err
+	}
+	if !actor.HasPermission(models.PermissionUsersImpersonate) {
+		return nil, errors.New("impersonator is no longer allowed to impersonate")
+	}
+
+	return &actor, nil
+}
+
+// GetRealUser returns the admin acting as the authenticated user when the request was
+// made with an impersonation token. The "user" context key holds the effective user.
+func GetRealUser(c *gin.Context) (models.User, bool) {
+	actor, exists := c.Get("real_user")
+	if !exists {
+		return models.User{}, false
+	}
+	return actor.(models.User), true
+}
+
+// IsImpersonating reports whether the request was made by an admin impersonating
+// the authenticated user.
+func IsImpersonating(c *gin.Context) bool {
+	_, exists := c.Get("real_user")
+	return exists
+}
+
+// auditImpersonatedRequest records a request made while impersonating, once the
+// handler has produced its response.
+func auditImpersonatedRequest(c *gin.Context, user *models.User) {
+	actor, ok := GetRealUser(c)
+	if !ok {
+		return
+	}
+
+	entry := models.ImpersonationAudit{
+		ActorID:    actor.ID,
+		UserID:     user.ID,
+		Action:     models.ImpersonationActionRequest,
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		StatusCode: c.Writer.Status(),
+		IPAddress:  c.ClientIP(),
+	}
+
+	log.Printf("Impersonation: admin %d as user %d: %s %s -> %d",
+		entry.ActorID, entry.UserID, entry.Method, entry.Path, entry.StatusCode)
+
+	if err := db.DB.Create(&entry).Error; err != nil {
+		log.Printf("Failed to record impersonated request: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cgzirim/ecommerce-api/dtos"
+	"github.com/cgzirim/ecommerce-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission aborts the request unless the authenticated user has been granted
+// the permission. It must run after LoadAuthUserMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authUser, exists := c.Get("user")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dtos.ErrorResponse{Error: "Unauthenticated, login is required"})
+			return
+		}
+
+		user := authUser.(models.User)
+
+		if !user.HasPermission(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, dtos.ErrorResponse{
+				Error: fmt.Sprintf("Unauthorized access, the %s permission is required", permission),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
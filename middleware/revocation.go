@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/models"
+)
+
+// revocationCacheTTL bounds how long a "not revoked" answer is trusted before the
+// database is consulted again, so revocations made by other instances propagate.
+const revocationCacheTTL = 30 * time.Second
+
+var revocations = struct {
+	sync.RWMutex
+	revoked map[string]time.Time // jti -> token expiry
+	checked map[string]time.Time // jti -> time the database was last consulted
+	swept   time.Time            // time stale entries were last dropped
+}{
+	revoked: make(map[string]time.Time),
+	checked: make(map[string]time.Time),
+}
+
+// RevokeToken adds a token to the revocation list until the token would have expired anyway.
+func RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	if jti == "" {
+		return errors.New("token has no jti")
+	}
+
+	entry := models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	err := db.DB.Where(models.RevokedToken{JTI: jti}).FirstOrCreate(&entry).Error
+	if err != nil {
+		return err
+	}
+
+	revocations.Lock()
+	revocations.revoked[jti] = expiresAt
+	delete(revocations.checked, jti)
+	revocations.Unlock()
+
+	if err := db.DB.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		log.Printf("Failed to purge expired token revocations: %v", err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the token with the given jti is on the revocation list.
+func IsTokenRevoked(jti string) (bool, error) {
+	now := time.Now()
+
+	revocations.RLock()
+	expiresAt, revoked := revocations.revoked[jti]
+	checkedAt, checked := revocations.checked[jti]
+	revocations.RUnlock()
+
+	if revoked {
+		return expiresAt.After(now), nil
+	}
+
+	if checked && now.Sub(checkedAt) < revocationCacheTTL {
+		return false, nil
+	}
+
+	var entries []models.RevokedToken
+	if err := db.DB.Where("jti = ?", jti).Limit(1).Find(&entries).Error; err != nil {
+		return false, err
+	}
+
+	revocations.Lock()
+	defer revocations.Unlock()
+
+	// Sweeping walks the whole cache, so it runs at most once per TTL rather than on every miss
+	if now.Sub(revocations.swept) >= revocationCacheTTL {
+		sweepRevocations(now)
+	}
+
+	if len(entries) > 0 {
+		revocations.revoked[jti] = entries[0].ExpiresAt
+		return entries[0].ExpiresAt.After(now), nil
+	}
+
+	revocations.checked[jti] = now
+	return false, nil
+}
+
+// sweepRevocations drops cache entries that no longer answer anything. The caller must
+// hold the write lock.
+func sweepRevocations(now time.Time) {
+	for key, at := range revocations.checked {
+		if now.Sub(at) >= revocationCacheTTL {
+			delete(revocations.checked, key)
+		}
+	}
+	for key, exp := range revocations.revoked {
+		if !exp.After(now) {
+			delete(revocations.revoked, key)
+		}
+	}
+	revocations.swept = now
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/cgzirim/ecommerce-api/db"
+	"github.com/cgzirim/ecommerce-api/models"
+)
+
+// sessionLastSeenResolution bounds how often last_seen_at is written for a busy session.
+const sessionLastSeenResolution = time.Minute
+
+// CheckSession rejects access tokens whose session has been terminated and records
+// that the session is still in use. Tokens issued before sessions were recorded have
+// no session and are accepted.
+func CheckSession(familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+
+	var session models.Session
+	if err := db.DB.Where("family_id = ?", familyID).Limit(1).Find(&session).Error; err != nil {
+		return err
+	}
+	if session.ID == 0 {
+		return nil
+	}
+
+	if session.RevokedAt != nil {
+		return errors.New("session has been terminated")
+	}
+
+	if now := time.Now(); now.Sub(session.LastSeenAt) > sessionLastSeenResolution {
+		if err := db.DB.Model(&session).UpdateColumn("last_seen_at", now).Error; err != nil {
+			log.Printf("Failed to record activity for session %d: %v", session.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -1,14 +1,20 @@
 package models
 
-// Address represents a user's address.
+// Address represents a user's address. Country holds an ISO 3166-1 alpha-2 code, and
+// the state and zip code follow the rules of the country. A user has at most one default
+// shipping and one default billing address.
 type Address struct {
 	BaseModel
 	FirstName     string `gorm:"varchar(255);not null" json:"first_name"`
 	LastName      string `gorm:"varchar(255);not null" json:"last_name"`
 	City          string `gorm:"varchar(50);not null" json:"city"`
+	State         string `gorm:"varchar(50);not null;default:''" json:"state"`
 	Country       string `gorm:"varchar(50);not null" json:"country"`
 	ZipCode       string `gorm:"varchar(15);not null" json:"zip_code"`
 	StreetAddress string `gorm:"varchar(255);not null" json:"street_address"`
 
-	UserID uint `gorm:"not null" json:"user_id"`
+	IsDefaultShipping bool `gorm:"not null;default:false" json:"is_default_shipping"`
+	IsDefaultBilling  bool `gorm:"not null;default:false" json:"is_default_billing"`
+
+	UserID uint `gorm:"not null;uniqueIndex:idx_addresses_default_shipping,where:is_default_shipping = true;uniqueIndex:idx_addresses_default_billing,where:is_default_billing = true" json:"user_id"`
 }
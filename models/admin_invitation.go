@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// AdminInvitation is a single-use, expiring invitation to register an admin account.
+// Only a hash of the invitation token is stored.
+type AdminInvitation struct {
+	BaseModel
+	Email       string     `json:"email"`
+	TokenHash   string     `gorm:"uniqueIndex;not null" json:"-"`
+	CreatedByID *uint      `json:"created_by_id"`
+	CreatedBy   *User      `gorm:"foreignKey:CreatedByID;constraint:OnDelete:SET NULL" json:"-"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt      *time.Time `json:"used_at"`
+	UsedByID    *uint      `json:"used_by_id"`
+	UsedBy      *User      `gorm:"foreignKey:UsedByID;constraint:OnDelete:SET NULL" json:"-"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+}
+
+// IsPending reports whether the invitation can still be used to register.
+func (invitation *AdminInvitation) IsPending() bool {
+	return invitation.UsedAt == nil && invitation.RevokedAt == nil && invitation.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// APIKeyPrefix starts every API key so leaked keys are easy to recognise in logs and scanners.
+const APIKeyPrefix = "eck_"
+
+// APIKey lets a server-to-server integration act on behalf of its owner without a
+// login. Only a hash of the key is stored; Prefix keeps enough of it to tell keys apart.
+// A key is limited to its scopes, and never grants more than its owner currently has.
+type APIKey struct {
+	BaseModel
+	Name       string       `gorm:"not null" json:"name"`
+	Prefix     string       `gorm:"not null" json:"prefix"`
+	KeyHash    string       `gorm:"uniqueIndex;not null" json:"-"`
+	UserID     uint         `gorm:"not null;index" json:"user_id"`
+	User       User         `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Scopes     []Permission `gorm:"many2many:api_key_permissions" json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	RevokedAt  *time.Time   `json:"revoked_at"`
+}
+
+// IsActive reports whether the key can still be used to authenticate.
+func (key *APIKey) IsActive() bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(time.Now()))
+}
+
+// ScopeNames returns the names of the permissions the key is scoped to.
+func (key *APIKey) ScopeNames() []string {
+	names := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		names = append(names, scope.Name)
+	}
+	return names
+}
@@ -1,8 +1,32 @@
 package models
 
+import "strings"
+
+// Category groups products. Categories form a tree through ParentID, and browsing a
+// category lists the products of its descendants as well.
 type Category struct {
 	BaseModel
-	Name        string `gorm:"not null"`
-	Description string
-	Products    []Product
+	Name        string     `gorm:"varchar(255);not null" json:"name"`
+	Slug        string     `gorm:"uniqueIndex;not null" json:"slug"`
+	Description string     `gorm:"type:text" json:"description"`
+	ParentID    *uint      `gorm:"index" json:"parent_id"`
+	Children    []Category `gorm:"foreignKey:ParentID;constraint:OnDelete:RESTRICT" json:"children,omitempty"`
+}
+
+// Slugify turns a category name into a slug of lowercase ASCII letters and digits
+// separated by hyphens, e.g. "Men's T-Shirts" becomes "mens-t-shirts".
+func Slugify(name string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r == '\'' || r == '’':
+			// Apostrophes don't separate words
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			slug.WriteRune(r)
+		case slug.Len() > 0 && !strings.HasSuffix(slug.String(), "-"):
+			slug.WriteByte('-')
+		}
+	}
+
+	return strings.TrimSuffix(slug.String(), "-")
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// EmailVerificationToken is a single-use, time-limited token proving that the user
+// controls Email. Only a hash of the token is stored.
+type EmailVerificationToken struct {
+	BaseModel
+	UserID    uint      `gorm:"index;not null"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Email     string    `gorm:"not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
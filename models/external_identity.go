@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ExternalIdentity links a user to their account at an OpenID Connect provider, so
+// that they can log in through the provider.
+type ExternalIdentity struct {
+	BaseModel
+	UserID      uint       `gorm:"index;not null" json:"-"`
+	User        User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Provider    string     `gorm:"uniqueIndex:idx_external_identities_provider_subject;not null" json:"provider"`
+	Subject     string     `gorm:"uniqueIndex:idx_external_identities_provider_subject;not null" json:"-"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// Impersonation audit actions.
+const (
+	ImpersonationActionStart   = "start"
+	ImpersonationActionRequest = "request"
+)
+
+// ImpersonationAudit records an admin starting to impersonate a user, and every request
+// they make while doing so.
+type ImpersonationAudit struct {
+	BaseModel
+	ActorID    uint   `gorm:"index;not null" json:"actor_id"`
+	Actor      User   `gorm:"foreignKey:ActorID;constraint:OnDelete:RESTRICT" json:"-"`
+	UserID     uint   `gorm:"index;not null" json:"user_id"`
+	User       User   `gorm:"foreignKey:UserID;constraint:OnDelete:RESTRICT" json:"-"`
+	Action     string `gorm:"not null" json:"action"`
+	Reason     string `json:"reason,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Path       string `json:"path,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	IPAddress  string `json:"ip_address"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// LoginThrottle counts consecutive failed logins for a single key, either an email
+// address ("email:<address>") or a client IP ("ip:<address>").
+type LoginThrottle struct {
+	BaseModel
+	Key           string    `gorm:"uniqueIndex;not null"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"not null"`
+	LockedUntil   *time.Time
+}
+
+// AccountLockout records a key being locked out after too many failed logins.
+type AccountLockout struct {
+	BaseModel
+	Key          string     `gorm:"index;not null" json:"key"`
+	Failures     int        `gorm:"not null" json:"failures"`
+	LockedUntil  time.Time  `gorm:"not null" json:"locked_until"`
+	UnlockedAt   *time.Time `json:"unlocked_at"`
+	UnlockedByID *uint      `json:"unlocked_by_id"`
+	UnlockedBy   *User      `gorm:"foreignKey:UnlockedByID;constraint:OnDelete:SET NULL" json:"-"`
+}
+
+// IsActive reports whether the lockout is still in force.
+func (lockout *AccountLockout) IsActive() bool {
+	return lockout.UnlockedAt == nil && lockout.LockedUntil.After(time.Now())
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// MFARecoveryCode is a single-use code that can stand in for a TOTP code when the
+// user has lost their authenticator. Only a hash of the code is stored.
+type MFARecoveryCode struct {
+	BaseModel
+	UserID   uint   `gorm:"index;not null"`
+	User     User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	CodeHash string `gorm:"index;not null"`
+	UsedAt   *time.Time
+}
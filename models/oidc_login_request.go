@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OIDCLoginRequest holds the state of a login at an OpenID Connect provider between
+// sending the user there and their return. Only a hash of the state is stored.
+type OIDCLoginRequest struct {
+	BaseModel
+	Provider     string    `gorm:"not null"`
+	StateHash    string    `gorm:"uniqueIndex;not null"`
+	Nonce        string    `gorm:"not null"`
+	CodeVerifier string    `gorm:"not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	UsedAt       *time.Time
+}
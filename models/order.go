@@ -1,15 +1,53 @@
 package models
 
-// Order represents an order placed by a user.
+// Order represents an order placed by a user. Orders are kept for accounting, so a user
+// with orders cannot be hard-deleted; deleted accounts are anonymized instead.
 type Order struct {
 	BaseModel
-	UserID     uint        `gorm:"not null" json:"-"`
-	User       User        `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user"`
-	AddressID  uint        `gorm:"default:null" json:"-"`
-	Address    Address     `gorm:"foreignKey:AddressID;constraint:OnDelete:SET NULL" json:"address"`
+	UserID uint `gorm:"not null" json:"-"`
+	User   User `gorm:"foreignKey:UserID;constraint:OnDelete:RESTRICT" json:"user"`
+
+	// AddressID is the address book entry the order was placed with. The entry may have
+	// been edited or deleted since; ShippingAddress is where the order ships to.
+	AddressID uint    `gorm:"default:null" json:"address_id,omitempty"`
+	Address   Address `gorm:"foreignKey:AddressID;constraint:OnDelete:SET NULL" json:"-"`
+
+	ShippingAddress OrderAddress `gorm:"embedded;embeddedPrefix:shipping_" json:"shipping_address"`
+	BillingAddress  OrderAddress `gorm:"embedded;embeddedPrefix:billing_" json:"billing_address"`
+
 	Total      float64     `gorm:"not null" json:"total"`
 	Status     string      `gorm:"default:'pending'" json:"status"`
 	OrderItems []OrderItem `gorm:"foreignKey:OrderID" json:"order_items"`
+
+	// StockReserved is set on orders that took their items from stock when placed.
+	// Orders placed before stock was tracked never took any, so cancelling them must
+	// not return any either.
+	StockReserved bool `gorm:"not null;default:false" json:"-"`
+}
+
+// OrderAddress is a copy of an address taken when an order is placed, so that later
+// changes to the user's address book do not change where past orders went.
+type OrderAddress struct {
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	StreetAddress string `json:"street_address"`
+	City          string `json:"city"`
+	State         string `json:"state"`
+	ZipCode       string `json:"zip_code"`
+	Country       string `json:"country"`
+}
+
+// NewOrderAddress returns a snapshot of address.
+func NewOrderAddress(address Address) OrderAddress {
+	return OrderAddress{
+		FirstName:     address.FirstName,
+		LastName:      address.LastName,
+		StreetAddress: address.StreetAddress,
+		City:          address.City,
+		State:         address.State,
+		ZipCode:       address.ZipCode,
+		Country:       address.Country,
+	}
 }
 
 const (
@@ -1,12 +1,16 @@
 package models
 
-// OrderItem represents an item in an order.
+// OrderItem represents an item in an order. Items of products with variants reference
+// the variant ordered, and keep its SKU in case the variant is changed later.
 type OrderItem struct {
 	BaseModel
-	Order     Order   `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"-"`
-	OrderID   uint    `gorm:"not null" json:"order_id"`
-	Product   Product `gorm:"foreignKey:ProductID;constraint:OnDelete:RESTRICT" json:"-"`
-	ProductID uint    `gorm:"not null" json:"product_id"`
-	Price     float64 `gorm:"not null;check:price_gt_zero,price > 0" json:"price"`
-	Quantity  int     `gorm:"not null;check:quantity_gt_zero,quantity > 0" json:"quantity"`
+	Order     Order           `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:"-"`
+	OrderID   uint            `gorm:"not null" json:"order_id"`
+	Product   Product         `gorm:"foreignKey:ProductID;constraint:OnDelete:RESTRICT" json:"-"`
+	ProductID uint            `gorm:"not null" json:"product_id"`
+	Variant   *ProductVariant `gorm:"foreignKey:VariantID;constraint:OnDelete:RESTRICT" json:"-"`
+	VariantID *uint           `gorm:"index" json:"variant_id,omitempty"`
+	SKU       string          `gorm:"not null;default:''" json:"sku,omitempty"`
+	Price     float64         `gorm:"not null;check:price_gt_zero,price > 0" json:"price"`
+	Quantity  int             `gorm:"not null;check:quantity_gt_zero,quantity > 0" json:"quantity"`
 }
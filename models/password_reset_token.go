@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PasswordResetToken is a single-use, time-limited token for resetting a forgotten
+// password. Only a hash of the token is stored.
+type PasswordResetToken struct {
+	BaseModel
+	UserID    uint      `gorm:"index;not null"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
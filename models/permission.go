@@ -0,0 +1,35 @@
+package models
+
+// Permission grants a single action, named "<resource>:<action>".
+type Permission struct {
+	BaseModel
+	Name        string `gorm:"uniqueIndex;not null" json:"name"`
+	Description string `json:"description"`
+}
+
+const (
+	PermissionProductsCreate     = "products:create"
+	PermissionProductsUpdate     = "products:update"
+	PermissionProductsDelete     = "products:delete"
+	PermissionCategoriesManage   = "categories:manage"
+	PermissionOrdersReadAll      = "orders:read_all"
+	PermissionOrdersUpdateStatus = "orders:update_status"
+	PermissionAdminsInvite       = "admins:invite"
+	PermissionRolesManage        = "roles:manage"
+	PermissionUsersManage        = "users:manage"
+	PermissionUsersImpersonate   = "users:impersonate"
+)
+
+// DefaultPermissions lists every permission the application checks for.
+var DefaultPermissions = []Permission{
+	{Name: PermissionProductsCreate, Description: "Create products"},
+	{Name: PermissionProductsUpdate, Description: "Update products"},
+	{Name: PermissionProductsDelete, Description: "Delete products"},
+	{Name: PermissionCategoriesManage, Description: "Create, update and delete product categories"},
+	{Name: PermissionOrdersReadAll, Description: "View the orders of any user"},
+	{Name: PermissionOrdersUpdateStatus, Description: "Change the status of any order"},
+	{Name: PermissionAdminsInvite, Description: "Invite new admins"},
+	{Name: PermissionRolesManage, Description: "Manage roles and assign them to users"},
+	{Name: PermissionUsersManage, Description: "Manage user accounts"},
+	{Name: PermissionUsersImpersonate, Description: "Act as a customer to reproduce their issues"},
+}
@@ -1,11 +1,18 @@
 package models
 
-// Product represents a product in the store.
+// Product represents a product in the store. Products that come in several sizes or
+// colours have Options and one of the Variants for each combination of their values,
+// and are ordered by variant; Price and Stock apply to products without variants.
+// Images is the product's gallery, in order.
 type Product struct {
 	BaseModel
-	Name        string  `gorm:"varchar(255);not null" json:"name"`
-	Category    string  `gorm:"varchar(255);not null" json:"category"`
-	Description string  `gorm:"type:text" json:"description"`
-	Price       float64 `gorm:"not null;check:price_gt_zero,price > 0" json:"price"`
-	Stock       int     `gorm:"not null;check:stock_non_negative,stock >= 0" json:"stock"`
+	Name        string           `gorm:"varchar(255);not null" json:"name"`
+	CategoryID  uint             `gorm:"index;default:null" json:"category_id"`
+	Category    *Category        `gorm:"foreignKey:CategoryID;constraint:OnDelete:RESTRICT" json:"category,omitempty"`
+	Description string           `gorm:"type:text" json:"description"`
+	Price       float64          `gorm:"not null;check:price_gt_zero,price > 0" json:"price"`
+	Stock       int              `gorm:"not null;check:stock_non_negative,stock >= 0" json:"stock"`
+	Options     []ProductOption  `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"options,omitempty"`
+	Variants    []ProductVariant `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"variants,omitempty"`
+	Images      []ProductImage   `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"images,omitempty"`
 }
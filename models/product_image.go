@@ -0,0 +1,49 @@
+package models
+
+import (
+	"github.com/cgzirim/ecommerce-api/thumbnail"
+)
+
+// ProductImage is an image in a product's gallery, shown in the order of Position.
+// The original and its thumbnails are kept in storage under StorageKey, and their
+// URLs are filled in before the image is returned by the API.
+type ProductImage struct {
+	BaseModel
+	ProductID   uint   `gorm:"not null;index" json:"product_id"`
+	Position    int    `gorm:"not null" json:"position"`
+	AltText     string `gorm:"varchar(255)" json:"alt_text"`
+	ContentType string `gorm:"not null" json:"content_type"`
+	Width       int    `gorm:"not null" json:"width"`
+	Height      int    `gorm:"not null" json:"height"`
+	StorageKey  string `gorm:"not null" json:"-"`
+
+	URL        string            `gorm:"-" json:"url"`
+	Thumbnails map[string]string `gorm:"-" json:"thumbnails"`
+}
+
+// ProductImageExtensions maps the content types accepted for product images to the
+// extension of the stored original.
+var ProductImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// OriginalKey is the storage key of the image as uploaded.
+func (image *ProductImage) OriginalKey() string {
+	return image.StorageKey + "/original" + ProductImageExtensions[image.ContentType]
+}
+
+// ThumbnailKey is the storage key of the thumbnail of the given size.
+func (image *ProductImage) ThumbnailKey(size string) string {
+	return image.StorageKey + "/" + size + ".jpg"
+}
+
+// Keys returns the storage keys of the original and all of its thumbnails.
+func (image *ProductImage) Keys() []string {
+	keys := []string{image.OriginalKey()}
+	for _, size := range thumbnail.Sizes {
+		keys = append(keys, image.ThumbnailKey(size.Name))
+	}
+	return keys
+}
@@ -0,0 +1,31 @@
+package models
+
+// ProductOption is a way a product varies, such as its size or colour, with the values
+// it comes in.
+type ProductOption struct {
+	BaseModel
+	ProductID uint                 `gorm:"not null;index" json:"-"`
+	Name      string               `gorm:"varchar(50);not null" json:"name"`
+	Position  int                  `gorm:"not null" json:"position"`
+	Values    []ProductOptionValue `gorm:"foreignKey:OptionID;constraint:OnDelete:CASCADE" json:"values"`
+}
+
+// ProductOptionValue is one of the values of a product option, such as "M" or "Red".
+type ProductOptionValue struct {
+	BaseModel
+	OptionID uint   `gorm:"not null;index" json:"option_id"`
+	Value    string `gorm:"varchar(50);not null" json:"value"`
+	Position int    `gorm:"not null" json:"position"`
+}
+
+// ProductVariant is a purchasable version of a product with one value of each of the
+// product's options. It has its own SKU, price, stock and weight in kilograms.
+type ProductVariant struct {
+	BaseModel
+	ProductID    uint                 `gorm:"not null;index" json:"product_id"`
+	SKU          string               `gorm:"uniqueIndex;not null" json:"sku"`
+	Price        float64              `gorm:"not null;check:price_gt_zero,price > 0" json:"price"`
+	Stock        int                  `gorm:"not null;check:stock_non_negative,stock >= 0" json:"stock"`
+	Weight       float64              `gorm:"not null;default:0;check:weight_non_negative,weight >= 0" json:"weight"`
+	OptionValues []ProductOptionValue `gorm:"many2many:product_variant_option_values;constraint:OnDelete:CASCADE" json:"option_values"`
+}
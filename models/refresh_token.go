@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RefreshToken records an issued refresh token so that it can be used exactly once.
+// Tokens minted by rotating one another share a FamilyID, which lets a replayed
+// token revoke every descendant issued after it.
+type RefreshToken struct {
+	BaseModel
+	JTI       string     `gorm:"uniqueIndex;not null" json:"-"`
+	FamilyID  string     `gorm:"index;not null" json:"-"`
+	UserID    uint       `gorm:"index;not null" json:"-"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
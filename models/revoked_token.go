@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RevokedToken is an entry in the access token revocation list. Entries only need to
+// outlive the token they revoke, after which they can be purged.
+type RevokedToken struct {
+	BaseModel
+	JTI       string    `gorm:"uniqueIndex;not null"`
+	UserID    uint      `gorm:"index;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
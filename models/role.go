@@ -0,0 +1,31 @@
+package models
+
+// Role is a named set of permissions. Every user has a base role named by User.Role
+// and may be assigned any number of additional roles.
+type Role struct {
+	BaseModel
+	Name        string       `gorm:"uniqueIndex;not null" json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+}
+
+// DefaultRolePermissions holds the permissions of the built-in roles. They are seeded
+// into the database and apply whenever a built-in role is missing from it.
+var DefaultRolePermissions = map[string][]string{
+	RoleCustomer: {},
+	RoleAdmin:    defaultPermissionNames(),
+}
+
+// IsBuiltIn reports whether the role is one of the roles users register with.
+func (role *Role) IsBuiltIn() bool {
+	_, ok := DefaultRolePermissions[role.Name]
+	return ok
+}
+
+func defaultPermissionNames() []string {
+	names := make([]string, 0, len(DefaultPermissions))
+	for _, permission := range DefaultPermissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Session describes one login on one device. It tracks the refresh token family
+// started by the login, so terminating a session revokes every token issued from it.
+type Session struct {
+	BaseModel
+	FamilyID   string     `gorm:"uniqueIndex;not null" json:"-"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// Current marks the session the request was made from.
+	Current bool `gorm:"-" json:"current"`
+}
+
+// IsActive reports whether the session can still be used.
+func (session *Session) IsActive() bool {
+	return session.RevokedAt == nil && session.ExpiresAt.After(time.Now())
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/cgzirim/ecommerce-api/keyring"
 	"github.com/cgzirim/ecommerce-api/utils"
 	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // User represents a user of the application
@@ -18,6 +20,28 @@ type User struct {
 	Password  string    `gorm:"not null" json:"-"`
 	Role      string    `gorm:"not null" json:"role"`
 	LastLogin time.Time `json:"last_login"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// MFASecret is set during enrollment; MFA is only enforced once MFAEnabledAt is set.
+	MFASecret       string     `json:"-"`
+	MFAEnabledAt    *time.Time `json:"mfa_enabled_at"`
+	MFALastUsedStep int64      `json:"-"`
+
+	// SuspendedAt is set while an admin has suspended the account; suspended users cannot log in.
+	SuspendedAt      *time.Time `json:"suspended_at"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+
+	// AnonymizedAt is set when the user deletes their account. The row is kept, stripped of
+	// personal data, so that their orders remain intact for accounting.
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+
+	// TokensValidAfter invalidates every token issued before it, e.g. after "log out everywhere".
+	TokensValidAfter *time.Time `json:"-"`
+
+	// Roles are assigned in addition to the base role named by Role.
+	Roles       []Role   `gorm:"many2many:user_roles" json:"roles,omitempty"`
+	Permissions []string `gorm:"-" json:"-"`
 }
 
 const (
@@ -29,36 +53,218 @@ func (user *User) IsAdmin() bool {
 	return user.Role == RoleAdmin
 }
 
+func (user *User) IsEmailVerified() bool {
+	return user.EmailVerifiedAt != nil
+}
+
+func (user *User) IsMFAEnabled() bool {
+	return user.MFAEnabledAt != nil
+}
+
+func (user *User) IsSuspended() bool {
+	return user.SuspendedAt != nil
+}
+
+func (user *User) IsAnonymized() bool {
+	return user.AnonymizedAt != nil
+}
+
+// LoadPermissions resolves the permissions granted by the user's base role and
+// assigned roles into user.Permissions.
+func (user *User) LoadPermissions(tx *gorm.DB) error {
+	var roles []Role
+	assigned := tx.Table("user_roles").Select("role_id").Where("user_id = ?", user.ID)
+	if err := tx.Preload("Permissions").Where("name = ?", user.Role).Or("id IN (?)", assigned).Find(&roles).Error; err != nil {
+		return err
+	}
+
+	granted := make(map[string]bool)
+	hasBaseRole := false
+	for _, role := range roles {
+		if role.Name == user.Role {
+			hasBaseRole = true
+		}
+		for _, permission := range role.Permissions {
+			granted[permission.Name] = true
+		}
+	}
+
+	if !hasBaseRole {
+		for _, name := range DefaultRolePermissions[user.Role] {
+			granted[name] = true
+		}
+	}
+
+	user.Permissions = make([]string, 0, len(granted))
+	for name := range granted {
+		user.Permissions = append(user.Permissions, name)
+	}
+
+	return nil
+}
+
+// HasPermission reports whether the user has been granted the permission. Until
+// LoadPermissions is called, the defaults for the user's base role apply.
+func (user *User) HasPermission(permission string) bool {
+	permissions := user.Permissions
+	if permissions == nil {
+		permissions = DefaultRolePermissions[user.Role]
+	}
+
+	for _, name := range permissions {
+		if name == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (user *User) IsValidPassword(password string) error {
 	bytePassword := []byte(password)
 	byteHashedPassword := []byte(user.Password)
 	return bcrypt.CompareHashAndPassword(byteHashedPassword, bytePassword)
 }
 
-func GenerateJwtTokens(user *User) (string, string, error) {
+const (
+	TokenTypeAccess       = "access"
+	TokenTypeRefresh      = "refresh"
+	TokenTypeMFAChallenge = "mfa_challenge"
+
+	AccessTokenLifetime        = time.Hour * 24
+	RefreshTokenLifetime       = time.Hour * 24 * 7
+	MFAChallengeTokenLifetime  = time.Minute * 5
+	ImpersonationTokenLifetime = time.Minute * 15
+)
+
+// TokenPair holds a signed access/refresh token pair together with the refresh
+// token record that must be persisted for the refresh token to be accepted.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	Refresh      RefreshToken
+}
+
+// GenerateJwtTokens signs a new access/refresh token pair for the user. The refresh
+// token joins the given token family, or starts a new family when familyID is empty.
+func GenerateJwtTokens(user *User, familyID string) (*TokenPair, error) {
+	if familyID == "" {
+		var err error
+		familyID, err = utils.GenerateRandomToken(16)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate token family: %w", err)
+		}
+	}
+
+	accessJTI, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token ID: %w", err)
+	}
+
+	refreshJTI, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token ID: %w", err)
+	}
+
+	now := time.Now()
+
 	accessClaims := jwt.MapClaims{
 		"userID": user.ID,
 		"role":   user.Role,
-		"exp":    time.Now().Add(time.Hour * 24).Unix(),
+		"type":   TokenTypeAccess,
+		"jti":    accessJTI,
+		"family": familyID,
+		"iat":    now.Unix(),
+		"exp":    now.Add(AccessTokenLifetime).Unix(),
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(utils.GetEnv("JWT_SECRET", "!2E")))
+	accessTokenString, err := keyring.Sign(accessClaims)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
+	refreshExpiresAt := now.Add(RefreshTokenLifetime)
 	refreshClaims := jwt.MapClaims{
 		"userID": user.ID,
 		"role":   user.Role,
-		"exp":    time.Now().Add(time.Hour * 24 * 7).Unix(),
+		"type":   TokenTypeRefresh,
+		"jti":    refreshJTI,
+		"family": familyID,
+		"iat":    now.Unix(),
+		"exp":    refreshExpiresAt.Unix(),
+	}
+
+	refreshTokenString, err := keyring.Sign(refreshClaims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessTokenString,
+		RefreshToken: refreshTokenString,
+		Refresh: RefreshToken{
+			JTI:       refreshJTI,
+			FamilyID:  familyID,
+			UserID:    user.ID,
+			ExpiresAt: refreshExpiresAt,
+		},
+	}, nil
+}
+
+// GenerateMFAChallengeToken signs a short-lived token proving that the user has passed
+// the password step of login. It can only be exchanged for full tokens together with a
+// second factor; enroll marks users who must set up MFA before they can log in.
+func GenerateMFAChallengeToken(user *User, enroll bool) (string, error) {
+	claims := jwt.MapClaims{
+		"userID": user.ID,
+		"type":   TokenTypeMFAChallenge,
+		"enroll": enroll,
+		"exp":    time.Now().Add(MFAChallengeTokenLifetime).Unix(),
+	}
+
+	tokenString, err := keyring.Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate MFA challenge token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// GenerateImpersonationToken signs a short-lived access token that lets actor act as
+// user. The actor is named in an "act" claim (RFC 8693) so that every request made
+// with the token can be attributed to them. No refresh token is issued.
+func GenerateImpersonationToken(user *User, actor *User) (string, time.Time, error) {
+	jti, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate access token ID: %w", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ImpersonationTokenLifetime)
+
+	claims := jwt.MapClaims{
+		"userID": user.ID,
+		"role":   user.Role,
+		"type":   TokenTypeAccess,
+		"jti":    jti,
+		"iat":    now.Unix(),
+		"exp":    expiresAt.Unix(),
+		"act": map[string]interface{}{
+			"userID": actor.ID,
+			"email":  actor.Email,
+		},
 	}
 
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(utils.GetEnv("JWT_SECRET", "!2E")))
+	tokenString, err := keyring.Sign(claims)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+		return "", time.Time{}, fmt.Errorf("failed to generate impersonation token: %w", err)
 	}
 
-	return accessTokenString, refreshTokenString, nil
+	return tokenString, expiresAt, nil
+}
+
+// ParseJwtToken verifies the signature and expiry of a token against the configured
+// keyring and returns its claims.
+func ParseJwtToken(tokenString string) (jwt.MapClaims, error) {
+	return keyring.Parse(tokenString)
 }
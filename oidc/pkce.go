@@ -0,0 +1,19 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/cgzirim/ecommerce-api/utils"
+)
+
+// GenerateCodeVerifier returns a random PKCE code verifier (RFC 7636).
+func GenerateCodeVerifier() (string, error) {
+	return utils.GenerateRandomToken(32)
+}
+
+// CodeChallenge returns the S256 code challenge for a code verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package oidc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cgzirim/ecommerce-api/utils"
+)
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]*Provider{}
+)
+
+// Set installs the providers users can log in with, replacing any installed before.
+func Set(list ...*Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	providers = make(map[string]*Provider, len(list))
+	for _, provider := range list {
+		providers[provider.Name] = provider
+	}
+}
+
+// Get returns the installed provider with the given name.
+func Get(name string) (*Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	provider, ok := providers[name]
+	return provider, ok
+}
+
+// Names returns the names of the installed providers in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadFromEnv builds the providers named in OIDC_PROVIDERS, a comma-separated list.
+// Each provider <NAME> is configured by OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL and OIDC_<NAME>_SCOPES.
+func LoadFromEnv() ([]*Provider, error) {
+	var list []*Provider
+
+	for _, name := range strings.Split(utils.GetEnv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		cfg := Config{
+			Name:         name,
+			IssuerURL:    utils.GetEnv(prefix + "ISSUER"),
+			ClientID:     utils.GetEnv(prefix + "CLIENT_ID"),
+			ClientSecret: utils.GetEnv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  utils.GetEnv(prefix+"REDIRECT_URL", "http://localhost:8080/v1/login/oidc/"+name+"/callback"),
+			Scopes:       strings.Fields(utils.GetEnv(prefix + "SCOPES")),
+		}
+
+		if cfg.IssuerURL == "" || cfg.ClientID == "" {
+			return nil, fmt.Errorf("OIDC provider %q needs %sISSUER and %sCLIENT_ID", name, prefix, prefix)
+		}
+
+		list = append(list, NewProvider(cfg))
+	}
+
+	return list, nil
+}
@@ -0,0 +1,182 @@
+package passwordpolicy
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxBreachedLineLength bounds a line of the breached password list: a 40 character
+// hash, a colon and an occurrence count.
+const maxBreachedLineLength = 128
+
+// BreachedList is an offline list of the SHA-1 hashes of breached passwords, in one of
+// the formats of the Have I Been Pwned k-anonymity hash-prefix lists:
+//
+//   - a directory of range files, one per 5 character hash prefix and named after it,
+//     e.g. "21BD1.txt", each holding the "SUFFIX:COUNT" lines the range API returns
+//     for that prefix;
+//   - a single file holding the full dump, one "HASH:COUNT" line per password with the
+//     full 40 character hash, sorted by hash.
+//
+// Only the hash is used; the count is optional, but entries with a count of 0 are
+// padding and are ignored.
+//
+// The list can hold hundreds of millions of entries, so it is searched on disk rather
+// than loaded into memory.
+type BreachedList struct {
+	// dir is set for a directory of range files.
+	dir string
+
+	// file and size are set for a full dump.
+	file *os.File
+	size int64
+}
+
+// breachedPrefixLength is the length of the hash prefixes range files are named after.
+const breachedPrefixLength = 5
+
+// OpenBreachedList opens the breached password list at path, which is either a
+// directory of range files or a full dump.
+func OpenBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	if info.IsDir() {
+		return &BreachedList{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+
+	list := &BreachedList{file: file, size: info.Size()}
+
+	if list.size > 0 {
+		_, line, err := list.lineAt(0)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if len(hashOf(line)) != sha1.Size*2 {
+			file.Close()
+			return nil, errors.New("breached password list is neither a directory of range files nor a list of SHA-1 hashes")
+		}
+	}
+
+	return list, nil
+}
+
+// Close closes the list's file.
+func (l *BreachedList) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Contains reports whether password appears in the list.
+func (l *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	if l.dir != "" {
+		return l.rangeContains(target[:breachedPrefixLength], target[breachedPrefixLength:])
+	}
+
+	// Binary search over byte offsets. lo always sits at the start of a line, and each
+	// step discards the line containing mid along with one side of it.
+	lo, hi := int64(0), l.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, line, err := l.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+
+		switch hash := hashOf(line); {
+		case hash == target:
+			return !isPadding(line), nil
+		case hash < target:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = start
+		}
+	}
+
+	return false, nil
+}
+
+// rangeContains reports whether the range file for prefix lists suffix. A missing
+// range file means that no breached password has a hash with that prefix.
+func (l *BreachedList) rangeContains(prefix, suffix string) (bool, error) {
+	content, err := os.ReadFile(filepath.Join(l.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if hashOf(line) == suffix && !isPadding(line) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// lineAt returns the line containing the byte at offset, without its line ending,
+// and the offset at which the line starts.
+func (l *BreachedList) lineAt(offset int64) (int64, []byte, error) {
+	from := offset - maxBreachedLineLength
+	if from < 0 {
+		from = 0
+	}
+
+	buf := make([]byte, offset-from+maxBreachedLineLength)
+	n, err := l.file.ReadAt(buf, from)
+	if n == 0 && err != nil {
+		return 0, nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	buf = buf[:n]
+
+	rel := offset - from
+	start := bytes.LastIndexByte(buf[:rel], '\n') + 1
+	if start == 0 && from > 0 {
+		return 0, nil, errors.New("breached password list has a line that is too long")
+	}
+
+	end := bytes.IndexByte(buf[start:], '\n')
+	if end < 0 {
+		if from+int64(n) < l.size {
+			return 0, nil, errors.New("breached password list has a line that is too long")
+		}
+		end = len(buf) - start
+	}
+
+	return from + int64(start), buf[start : start+end], nil
+}
+
+// hashOf returns the upper-cased hash, or hash suffix, at the start of a line of the list.
+func hashOf(line []byte) string {
+	hash, _, _ := strings.Cut(strings.TrimRight(string(line), "\r"), ":")
+	return strings.ToUpper(strings.TrimSpace(hash))
+}
+
+// isPadding reports whether a line of the list is padding added by the range API,
+// which gives such lines a count of 0.
+func isPadding(line []byte) bool {
+	_, count, _ := strings.Cut(strings.TrimRight(string(line), "\r"), ":")
+	return strings.TrimSpace(count) == "0"
+}
@@ -0,0 +1,178 @@
+// Package passwordpolicy decides whether a password is acceptable for an account.
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cgzirim/ecommerce-api/utils"
+)
+
+// BcryptMaxLength is the number of bytes bcrypt hashes; anything beyond is ignored.
+const BcryptMaxLength = 72
+
+// Violation is returned when a password does not satisfy the policy. Its message is
+// meant to be shown to the user.
+type Violation string
+
+func (v Violation) Error() string {
+	return string(v)
+}
+
+// Policy holds the rules passwords must satisfy.
+type Policy struct {
+	// MinLength is the minimum number of characters.
+	MinLength int
+	// MaxLength is the maximum number of bytes, at most BcryptMaxLength.
+	MaxLength int
+	// MinCharacterClasses is how many of lowercase letters, uppercase letters, digits
+	// and symbols must appear.
+	MinCharacterClasses int
+	// Breached, if set, rejects passwords that appear in a list of breached passwords.
+	Breached *BreachedList
+}
+
+// Default returns the policy used when none is configured.
+func Default() *Policy {
+	return &Policy{MinLength: 8, MaxLength: BcryptMaxLength, MinCharacterClasses: 1}
+}
+
+// Check returns a Violation if password is not acceptable for the account with the
+// given email, or another error if the breached password list cannot be read.
+func (p *Policy) Check(password, email string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return Violation(fmt.Sprintf("Password must be at least %d characters long.", p.MinLength))
+	}
+
+	if len(password) > p.MaxLength {
+		return Violation(fmt.Sprintf("Password must be at most %d bytes long.", p.MaxLength))
+	}
+
+	if characterClasses(password) < p.MinCharacterClasses {
+		return Violation(fmt.Sprintf(
+			"Password must contain at least %d of: lowercase letters, uppercase letters, digits and symbols.",
+			p.MinCharacterClasses,
+		))
+	}
+
+	if derivedFromEmail(password, email) {
+		return Violation("Password must not be based on your email address.")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			return Violation("This password has appeared in a data breach, please choose a different one.")
+		}
+	}
+
+	return nil
+}
+
+// characterClasses counts the kinds of characters present in password.
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// derivedFromEmail reports whether password is the email address, or contains its
+// local part, ignoring case and punctuation.
+func derivedFromEmail(password, email string) bool {
+	if email == "" {
+		return false
+	}
+
+	normalize := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, s)
+	}
+
+	normalized := normalize(password)
+	if normalized == normalize(email) {
+		return true
+	}
+
+	localPart, _, _ := strings.Cut(email, "@")
+	localPart = normalize(localPart)
+
+	// Very short local parts would reject unrelated passwords by coincidence.
+	return len(localPart) >= 4 && strings.Contains(normalized, localPart)
+}
+
+// LoadFromEnv builds the policy described by the PASSWORD_* environment variables.
+func LoadFromEnv() (*Policy, error) {
+	p := Default()
+	p.MinLength = utils.GetEnvInt("PASSWORD_MIN_LENGTH", p.MinLength)
+	p.MaxLength = utils.GetEnvInt("PASSWORD_MAX_LENGTH", p.MaxLength)
+	p.MinCharacterClasses = utils.GetEnvInt("PASSWORD_MIN_CHARACTER_CLASSES", p.MinCharacterClasses)
+
+	if p.MinLength < 1 || p.MaxLength < p.MinLength || p.MaxLength > BcryptMaxLength {
+		return nil, fmt.Errorf("password length limits must satisfy 1 <= PASSWORD_MIN_LENGTH <= PASSWORD_MAX_LENGTH <= %d", BcryptMaxLength)
+	}
+	if p.MinCharacterClasses < 0 || p.MinCharacterClasses > 4 {
+		return nil, fmt.Errorf("PASSWORD_MIN_CHARACTER_CLASSES must be between 0 and 4")
+	}
+
+	if path := utils.GetEnv("PASSWORD_BREACHED_LIST"); path != "" {
+		list, err := OpenBreachedList(path)
+		if err != nil {
+			return nil, err
+		}
+		p.Breached = list
+	}
+
+	return p, nil
+}
+
+var (
+	mu      sync.RWMutex
+	current = Default()
+)
+
+// Set installs the policy returned by Current.
+func Set(p *Policy) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = p
+}
+
+// Current returns the installed policy, or the default policy if none has been set.
+func Current() *Policy {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Check checks password against the installed policy.
+func Check(password, email string) error {
+	return Current().Check(password, email)
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalRoute is the path the API serves files in local storage from.
+const LocalRoute = "/uploads"
+
+// LocalConfig configures local storage.
+type LocalConfig struct {
+	// Dir is the directory files are stored in, "uploads" if empty.
+	Dir string
+	// PublicURL is prepended to keys to build URLs, LocalRoute if empty. Set it to the
+	// API's public address, e.g. https://api.example.com/uploads, for absolute URLs.
+	PublicURL string
+}
+
+// Local stores files in a directory on the API server, for development and single
+// server deployments. The API serves them from LocalRoute.
+type Local struct {
+	Dir       string
+	publicURL string
+}
+
+// NewLocal returns local storage for cfg.
+func NewLocal(cfg LocalConfig) *Local {
+	if cfg.Dir == "" {
+		cfg.Dir = "uploads"
+	}
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = LocalRoute
+	}
+
+	return &Local{Dir: cfg.Dir, publicURL: strings.TrimSuffix(cfg.PublicURL, "/")}
+}
+
+// Put writes content to a temporary file and renames it into place, so that a file
+// is never served half written.
+func (l *Local) Put(ctx context.Context, key string, content []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	path := filepath.Join(l.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Delete removes the file stored under key.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(l.Dir, filepath.FromSlash(key)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// URL returns the URL of the file stored under key.
+func (l *Local) URL(key string) string {
+	return l.publicURL + "/" + key
+}
@@ -0,0 +1,95 @@
+// Package thumbnail scales uploaded images down to the sizes shown in the storefront.
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+)
+
+// JPEGQuality is the quality thumbnails are encoded with.
+const JPEGQuality = 85
+
+// Size is a thumbnail size, named for use in URLs and responses.
+type Size struct {
+	Name string
+	// MaxDimension bounds the width and the height of the thumbnail.
+	MaxDimension int
+}
+
+// Sizes are the thumbnails generated for every image, smallest first.
+var Sizes = []Size{
+	{Name: "small", MaxDimension: 160},
+	{Name: "medium", MaxDimension: 480},
+	{Name: "large", MaxDimension: 1024},
+}
+
+// Generate returns a JPEG thumbnail of img in each of Sizes, keyed by size name.
+// Thumbnails keep the aspect ratio of img and are never larger than it. Transparent
+// areas become white.
+func Generate(img image.Image) (map[string][]byte, error) {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+
+	thumbnails := make(map[string][]byte, len(Sizes))
+	for _, size := range Sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, Fit(flat, size.MaxDimension), &jpeg.Options{Quality: JPEGQuality}); err != nil {
+			return nil, err
+		}
+		thumbnails[size.Name] = buf.Bytes()
+	}
+
+	return thumbnails, nil
+}
+
+// Fit scales src, whose bounds must start at the origin, down to fit within
+// maxDimension, averaging the source pixels that make up each pixel of the result.
+// Opacity is ignored.
+func Fit(src *image.RGBA, maxDimension int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+
+	width, height := srcWidth, srcHeight
+	if width > maxDimension || height > maxDimension {
+		if width >= height {
+			width, height = maxDimension, max(1, srcHeight*maxDimension/srcWidth)
+		} else {
+			width, height = max(1, srcWidth*maxDimension/srcHeight), maxDimension
+		}
+	}
+	if width == srcWidth && height == srcHeight {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+
+			var r, g, b, count int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += int(row[i])
+					g += int(row[i+1])
+					b += int(row[i+2])
+				}
+				count += x1 - x0
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / count)
+			dst.Pix[i+1] = uint8(g / count)
+			dst.Pix[i+2] = uint8(b / count)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+
+	return dst
+}
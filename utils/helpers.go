@@ -1,6 +1,14 @@
 package utils
 
-import "os"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"time"
+)
 
 // GetEnv returns the value of an environment variable, or a fallback value if it is not set.
 func GetEnv(key string, fallback ...string) string {
@@ -13,3 +21,38 @@ func GetEnv(key string, fallback ...string) string {
 	}
 	return ""
 }
+
+// GenerateRandomToken returns a URL-safe random string built from numBytes bytes of entropy.
+func GenerateRandomToken(numBytes int) (string, error) {
+	buf := make([]byte, numBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the hex-encoded SHA-256 digest of a token, for storing secrets at rest.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetEnvInt returns an environment variable parsed as an integer, or fallback if it is unset or invalid.
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(GetEnv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvDuration returns an environment variable parsed as a duration such as "15m",
+// or fallback if it is unset or invalid.
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(GetEnv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}